@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"hypr-input-switcher/internal/control"
+
+	"github.com/spf13/cobra"
+)
+
+// ctlCmd groups the commands talking to a running daemon
+var ctlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "Control a running hypr-input-switcher",
+	Long:  "Send requests to a running hypr-input-switcher over its control socket",
+}
+
+var ctlStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Print the switcher and notification status",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendControlRequest(cmd, &control.Request{Command: control.CommandStatus})
+	},
+}
+
+var ctlSwitchCmd = &cobra.Command{
+	Use:   "switch <input-method>",
+	Short: "Switch to an input method",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendControlRequest(cmd, &control.Request{Command: control.CommandSwitch, InputMethod: args[0]})
+	},
+}
+
+var ctlReloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload the configuration file",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendControlRequest(cmd, &control.Request{Command: control.CommandReload})
+	},
+}
+
+var ctlPauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause automatic switching",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendControlRequest(cmd, &control.Request{Command: control.CommandPause})
+	},
+}
+
+var ctlResumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume automatic switching",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendControlRequest(cmd, &control.Request{Command: control.CommandResume})
+	},
+}
+
+var ctlMatchCmd = &cobra.Command{
+	Use:   "match",
+	Short: "Print the focused window and the rule matching it",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendControlRequest(cmd, &control.Request{Command: control.CommandMatch})
+	},
+}
+
+func init() {
+	ctlCmd.PersistentFlags().String("socket", "", fmt.Sprintf("control socket (default: %s)", control.SocketPath()))
+
+	ctlCmd.AddCommand(ctlStatusCmd, ctlSwitchCmd, ctlReloadCmd, ctlPauseCmd, ctlResumeCmd, ctlMatchCmd)
+	rootCmd.AddCommand(ctlCmd)
+}
+
+// sendControlRequest sends a request to the daemon and prints the data of
+// the response as JSON
+func sendControlRequest(cmd *cobra.Command, request *control.Request) error {
+	// Usage is for argument errors, not for a daemon that isn't running
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true // main prints it
+
+	socketPath, _ := cmd.Flags().GetString("socket")
+
+	response, err := control.NewClient(socketPath).Send(request)
+	if err != nil {
+		return err
+	}
+
+	if response.Data == nil {
+		return nil
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(response.Data)
+}
@@ -9,8 +9,10 @@ import (
 	"syscall"
 
 	"hypr-input-switcher/internal/config"
+	"hypr-input-switcher/internal/control"
 	"hypr-input-switcher/internal/inputmethod"
 	"hypr-input-switcher/internal/notification"
+	"hypr-input-switcher/internal/state"
 	"hypr-input-switcher/pkg/logger"
 )
 
@@ -20,8 +22,14 @@ type Application struct {
 	configManager *config.Manager
 	switcher      *inputmethod.Switcher
 	notifier      *notification.Notifier
+	classMemory   *state.Store
+	control       *control.Server
+	dbus          *control.DBusService
 	watchConfig   bool
 
+	// Guards switcher and notifier, which are replaced on reload
+	mutex sync.RWMutex
+
 	// Add fields to manage the monitoring context
 	monitorCtx    context.Context
 	monitorCancel context.CancelFunc
@@ -51,13 +59,27 @@ func (app *Application) Run(configPath string, watchConfig bool) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	// Load learned input methods
+	app.classMemory = state.NewStore(state.DefaultPath())
+	if err := app.classMemory.Load(); err != nil {
+		logger.Warningf("Failed to load learned input methods: %v", err)
+	}
+	defer func() {
+		// Save changes still waiting for the background save
+		if err := app.classMemory.Flush(); err != nil {
+			logger.Warningf("Failed to save learned input methods: %v", err)
+		}
+	}()
+
 	app.config = cfg
 	app.configManager = configManager
 	app.switcher = inputmethod.NewSwitcher(cfg)
 	app.notifier = notification.NewNotifier(cfg)
 
-	// Set notifier for switcher
+	// Set notifier, learned input methods and change listener for switcher
 	app.switcher.SetNotifier(app.notifier)
+	app.switcher.SetClassMemory(app.classMemory)
+	app.switcher.SetChangeListener(app.onInputMethodChanged)
 
 	// Register config change callback
 	app.configManager.AddCallback(app.onConfigChanged)
@@ -72,6 +94,26 @@ func (app *Application) Run(configPath string, watchConfig bool) error {
 		}
 	}
 
+	// Start the control socket
+	app.control = control.NewServer(control.SocketPath(), app)
+	if err := app.control.Start(); err != nil {
+		logger.Warningf("Failed to start control socket: %v", err)
+		// Continue without control socket
+		app.control = nil
+	} else {
+		defer app.control.Close()
+	}
+
+	// Export the D-Bus service
+	app.dbus = control.NewDBusService(app)
+	if err := app.dbus.Start(); err != nil {
+		logger.Warningf("Failed to export D-Bus service: %v", err)
+		// Continue without D-Bus service
+		app.dbus = nil
+	} else {
+		defer app.dbus.Close()
+	}
+
 	// Set up signal handling
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -113,8 +155,9 @@ func (app *Application) runMonitoringLoop(ctx context.Context) error {
 
 		// Start monitoring in a goroutine
 		errChan := make(chan error, 1)
+		switcher := app.currentSwitcher()
 		go func() {
-			errChan <- app.switcher.MonitorAndSwitch(monitorCtx)
+			errChan <- switcher.MonitorAndSwitch(monitorCtx)
 		}()
 
 		// Wait for either completion, restart signal, or parent context cancellation
@@ -152,17 +195,30 @@ func (app *Application) runMonitoringLoop(ctx context.Context) error {
 func (app *Application) onConfigChanged(newConfig *config.Config) {
 	logger.Info("Applying new configuration...")
 
+	app.mutex.Lock()
+
 	// Update config
 	app.config = newConfig
 
-	// Recreate switcher with new config
+	// Recreate switcher with new config, staying paused if it was and
+	// keeping the input methods remembered per window
+	paused := app.switcher.Paused()
+	windowMemory := app.switcher.WindowMemory()
 	app.switcher = inputmethod.NewSwitcher(newConfig)
+	app.switcher.SetWindowMemory(windowMemory)
+	if paused {
+		app.switcher.Pause()
+	}
 
 	// Recreate notifier with new config
 	app.notifier = notification.NewNotifier(newConfig)
 
-	// Set notifier for switcher
+	// Set notifier, learned input methods and change listener for switcher
 	app.switcher.SetNotifier(app.notifier)
+	app.switcher.SetClassMemory(app.classMemory)
+	app.switcher.SetChangeListener(app.onInputMethodChanged)
+
+	app.mutex.Unlock()
 
 	logger.Info("Configuration applied successfully")
 
@@ -173,3 +229,61 @@ func (app *Application) onConfigChanged(newConfig *config.Config) {
 		// Channel is full, restart is already pending
 	}
 }
+
+// currentSwitcher returns the switcher of the current configuration
+func (app *Application) currentSwitcher() *inputmethod.Switcher {
+	app.mutex.RLock()
+	defer app.mutex.RUnlock()
+	return app.switcher
+}
+
+// onInputMethodChanged publishes input method switches on D-Bus
+func (app *Application) onInputMethodChanged(change inputmethod.Change) {
+	if app.dbus != nil {
+		app.dbus.EmitInputMethodChanged(change.InputMethod, change.Class, change.Title, change.Reason)
+	}
+}
+
+// CurrentInputMethod answers the D-Bus CurrentInputMethod property
+func (app *Application) CurrentInputMethod() string {
+	return app.currentSwitcher().CurrentInputMethod()
+}
+
+// Status answers the control status request
+func (app *Application) Status() map[string]interface{} {
+	app.mutex.RLock()
+	switcher, notifier := app.switcher, app.notifier
+	app.mutex.RUnlock()
+
+	return map[string]interface{}{
+		"switcher":      switcher.GetStatus(),
+		"notifications": notifier.GetStatus(),
+		"config":        app.configManager.GetConfigPath(),
+	}
+}
+
+// Switch answers the control switch request
+func (app *Application) Switch(inputMethod string) error {
+	return app.currentSwitcher().SwitchInputMethod(inputMethod)
+}
+
+// Reload answers the control reload request. The new configuration is
+// applied asynchronously, like a change picked up by the file watcher.
+func (app *Application) Reload() error {
+	return app.configManager.Reload()
+}
+
+// Pause answers the control pause request
+func (app *Application) Pause() {
+	app.currentSwitcher().Pause()
+}
+
+// Resume answers the control resume request
+func (app *Application) Resume() error {
+	return app.currentSwitcher().Resume()
+}
+
+// Match answers the control match request
+func (app *Application) Match() map[string]interface{} {
+	return app.currentSwitcher().RuleMatch()
+}
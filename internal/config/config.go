@@ -262,12 +262,18 @@ func (m *Manager) handleFileChangeDebounced() {
 
 // Handle actual file change
 func (m *Manager) handleFileChange() {
+	if err := m.Reload(); err != nil {
+		logger.Errorf("Failed to reload config: %v", err)
+	}
+}
+
+// Reload loads the configuration file again and notifies the callbacks
+func (m *Manager) Reload() error {
 	logger.Debug("Reloading configuration...")
 
 	newConfig, err := m.Load()
 	if err != nil {
-		logger.Errorf("Failed to reload config: %v", err)
-		return
+		return err
 	}
 
 	logger.Debug("Configuration reloaded successfully")
@@ -281,6 +287,8 @@ func (m *Manager) handleFileChange() {
 	for _, callback := range callbacks {
 		go callback(newConfig)
 	}
+
+	return nil
 }
 
 // getDefaultConfigPath returns the default configuration file path
@@ -0,0 +1,12 @@
+package config
+
+// builtinGameRules match common game windows
+var builtinGameRules = []ClientRule{
+	{Class: `^steam_app_`}, // games started through Steam
+}
+
+// CompileGameRules compiles the built-in game rules followed by the user's
+// rules. Errors name the index of the offending user rule.
+func CompileGameRules(rules []ClientRule) (*RuleSet, error) {
+	return compileExtendedRules(builtinGameRules, rules)
+}
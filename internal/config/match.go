@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Match modes for client rule patterns
+const (
+	MatchAuto     = "auto"     // the default, same as regex
+	MatchExact    = "exact"    // the whole value equals the pattern
+	MatchGlob     = "glob"     // shell-style wildcards: *, ? and [...]
+	MatchRegex    = "regex"    // the regex matches anywhere in the value
+	MatchContains = "contains" // the value contains the pattern
+)
+
+// matchModes lists the valid match modes
+var matchModes = []string{MatchAuto, MatchExact, MatchGlob, MatchRegex, MatchContains}
+
+// MatchMode returns the match mode of a rule field, such as "class"
+func (r *ClientRule) MatchMode(field string) string {
+	if mode, exists := r.MatchModes[field]; exists && mode != "" {
+		return mode
+	}
+	if r.Match != "" {
+		return r.Match
+	}
+	return MatchAuto
+}
+
+// Pattern is a compiled rule pattern
+type Pattern struct {
+	source     string         // pattern as written in the configuration
+	mode       string         // match mode
+	ignoreCase bool           // match case-insensitively
+	text       string         // pattern for exact and contains, lowercased if ignoring case
+	regex      *regexp.Regexp // compiled pattern for glob, regex and auto
+}
+
+// CompilePattern compiles a pattern for the given match mode
+func CompilePattern(mode string, ignoreCase bool, pattern string) (*Pattern, error) {
+	if mode == "" {
+		mode = MatchAuto
+	}
+
+	compiled := &Pattern{source: pattern, mode: mode, ignoreCase: ignoreCase, text: pattern}
+
+	switch mode {
+	case MatchExact, MatchContains:
+		if ignoreCase {
+			compiled.text = strings.ToLower(pattern)
+		}
+		return compiled, nil
+
+	case MatchGlob:
+		pattern = globToRegex(pattern)
+
+	case MatchRegex, MatchAuto:
+
+	default:
+		return nil, fmt.Errorf("unknown match mode %q (available: %v)", mode, matchModes)
+	}
+
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", compiled.source, err)
+	}
+
+	compiled.regex = regex
+	return compiled, nil
+}
+
+// Match matches a value against the pattern. Empty values never match.
+func (p *Pattern) Match(text string) bool {
+	if text == "" || p.text == "" {
+		return false
+	}
+
+	switch p.mode {
+	case MatchExact:
+		if p.ignoreCase {
+			return strings.EqualFold(p.text, text)
+		}
+		return p.text == text
+
+	case MatchContains:
+		if p.ignoreCase {
+			text = strings.ToLower(text)
+		}
+		return strings.Contains(text, p.text)
+
+	default:
+		return p.regex.MatchString(text)
+	}
+}
+
+// String returns the pattern as written in the configuration
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Mode returns the match mode of the pattern
+func (p *Pattern) Mode() string {
+	return p.mode
+}
+
+// globToRegex converts a glob into an anchored regex
+func globToRegex(glob string) string {
+	var builder strings.Builder
+	builder.WriteString("^")
+
+	inClass := false
+	for i, r := range glob {
+		switch {
+		case inClass && r == '!' && i > 0 && glob[i-1] == '[':
+			// Negated character class
+			builder.WriteRune('^')
+		case inClass:
+			if r == ']' {
+				inClass = false
+			}
+			if r == '\\' {
+				builder.WriteString(`\\`)
+				continue
+			}
+			builder.WriteRune(r)
+		case r == '*':
+			builder.WriteString(".*")
+		case r == '?':
+			builder.WriteString(".")
+		case r == '[':
+			inClass = true
+			builder.WriteRune(r)
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	builder.WriteString("$")
+	return builder.String()
+}
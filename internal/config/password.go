@@ -0,0 +1,18 @@
+package config
+
+// builtinPasswordRules match common credential prompts. Rules without a
+// window identifying condition never match, so title rules use any class.
+var builtinPasswordRules = []ClientRule{
+	{Class: `(?i)pinentry`},                          // GnuPG pinentry-gtk, pinentry-qt, ...
+	{Class: `(?i)(polkit|policykit)`},                // polkit-gnome, polkit-kde, hyprpolkitagent, ...
+	{Class: `(?i)askpass`},                           // ssh-askpass, ksshaskpass, ...
+	{Class: `(?i)keepassxc`, Title: `(?i)unlock`},    // KeePassXC unlock dialog
+	{Class: `^hyprlock$`},                            // hyprlock
+	{Class: `.`, Title: `(?i)(password|passphrase)`}, // any window asking for one
+}
+
+// CompilePasswordRules compiles the built-in credential prompt rules followed
+// by the user's rules. Errors name the index of the offending user rule.
+func CompilePasswordRules(rules []ClientRule) (*RuleSet, error) {
+	return compileExtendedRules(builtinPasswordRules, rules)
+}
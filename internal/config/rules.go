@@ -0,0 +1,292 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hypr-input-switcher/pkg/logger"
+)
+
+// CompiledRule is a client rule with its patterns compiled. Patterns of
+// unset fields are nil.
+type CompiledRule struct {
+	Index   int         // position in client_rules, or in the built-in or user rules of a mode
+	Builtin bool        // built-in rule of password_safe or game_mode
+	Rule    *ClientRule // source rule
+
+	Class             *Pattern
+	Title             *Pattern
+	NotClass          *Pattern
+	NotTitle          *Pattern
+	InitialClass      *Pattern
+	InitialTitle      *Pattern
+	Workspace         *Pattern // workspace name; IDs and "special" are matched directly
+	Monitor           *Pattern
+	Layer             *Pattern
+	AppID             *Pattern
+	Exe               *Pattern
+	Cmdline           *Pattern
+	ForegroundExe     *Pattern
+	ForegroundCmdline *Pattern
+
+	Specificity int      // how narrowly the rule's conditions select windows
+	Conditions  []string // conditions contributing to Specificity, e.g. "class (exact)"
+}
+
+// RuleSet holds the compiled client rules in evaluation order, so the
+// first matching rule wins
+type RuleSet struct {
+	Rules      []CompiledRule
+	Evaluation string // first_match or specificity
+
+	UsesProcess    bool // some rule matches on the window's process
+	UsesForeground bool // some rule matches on a terminal's foreground process
+}
+
+// CompileRules compiles the patterns of all client rules and orders them
+// for the given evaluation mode. Errors name the index of the offending rule.
+func CompileRules(rules []ClientRule, evaluation string) (*RuleSet, error) {
+	if evaluation == "" {
+		evaluation = EvaluationFirstMatch
+	}
+
+	ruleSet := &RuleSet{Rules: make([]CompiledRule, 0, len(rules)), Evaluation: evaluation}
+
+	for i := range rules {
+		compiled, err := compileRule(i, &rules[i])
+		if err != nil {
+			return nil, fmt.Errorf("client rule %d: %w", i, err)
+		}
+
+		ruleSet.Rules = append(ruleSet.Rules, *compiled)
+
+		if compiled.AppID != nil || compiled.Exe != nil || compiled.Cmdline != nil {
+			ruleSet.UsesProcess = true
+		}
+		if compiled.ForegroundExe != nil || compiled.ForegroundCmdline != nil {
+			ruleSet.UsesProcess = true
+			ruleSet.UsesForeground = true
+		}
+	}
+
+	// Ranks are static, so sorting once lets the first match win in both modes
+	sort.SliceStable(ruleSet.Rules, func(i, j int) bool {
+		return ruleSet.Rules[i].Outranks(&ruleSet.Rules[j], evaluation)
+	})
+
+	return ruleSet, nil
+}
+
+// compileExtendedRules compiles built-in rules followed by the user rules
+// extending them, in first match order. Errors name the index of the
+// offending user rule.
+func compileExtendedRules(builtin, rules []ClientRule) (*RuleSet, error) {
+	ruleSet, err := CompileRules(builtin, EvaluationFirstMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := CompileRules(rules, EvaluationFirstMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	// User rules keep their own indexes, which errors and logs refer to
+	for i := range ruleSet.Rules {
+		ruleSet.Rules[i].Builtin = true
+	}
+
+	ruleSet.Rules = append(ruleSet.Rules, user.Rules...)
+	ruleSet.UsesProcess = ruleSet.UsesProcess || user.UsesProcess
+	ruleSet.UsesForeground = ruleSet.UsesForeground || user.UsesForeground
+
+	return ruleSet, nil
+}
+
+// Describe names the rule for logs, e.g. "rule 2" or "built-in rule 0"
+func (r *CompiledRule) Describe() string {
+	if r.Builtin {
+		return fmt.Sprintf("built-in rule %d", r.Index)
+	}
+	return fmt.Sprintf("rule %d", r.Index)
+}
+
+// Outranks checks if a rule wins over another rule matching the same window.
+// Rules of equal rank are left in configuration order.
+func (r *CompiledRule) Outranks(other *CompiledRule, evaluation string) bool {
+	if r.Rule.Priority != other.Rule.Priority {
+		return r.Rule.Priority > other.Rule.Priority
+	}
+	if evaluation == EvaluationSpecificity && r.Specificity != other.Specificity {
+		return r.Specificity > other.Specificity
+	}
+	return false
+}
+
+// Explain describes why a rule won over the runner-up, the next matching
+// rule in evaluation order, which may be nil
+func (r *CompiledRule) Explain(runnerUp *CompiledRule, evaluation string) string {
+	if runnerUp == nil {
+		return "only matching rule"
+	}
+
+	switch {
+	case r.Rule.Priority != runnerUp.Rule.Priority:
+		return fmt.Sprintf("priority %d outranks rule %d (priority %d)", r.Rule.Priority, runnerUp.Index, runnerUp.Rule.Priority)
+	case evaluation != EvaluationSpecificity:
+		return fmt.Sprintf("first matching rule, before rule %d", runnerUp.Index)
+	case r.Specificity != runnerUp.Specificity:
+		return fmt.Sprintf("more specific than rule %d (%d > %d: %s)", runnerUp.Index, r.Specificity, runnerUp.Specificity, strings.Join(r.Conditions, ", "))
+	default:
+		return fmt.Sprintf("as specific as rule %d (%d), but listed first", runnerUp.Index, r.Specificity)
+	}
+}
+
+// Specificity weights. Every condition counts for more than any match mode,
+// so class+title beats class alone, and among equal conditions exact
+// patterns beat globs, which beat regexes and substrings.
+const (
+	conditionWeight = 10
+	exactWeight     = 4
+	globWeight      = 3
+	regexWeight     = 2
+	containsWeight  = 1
+)
+
+// patternWeight returns the specificity weight of a match mode
+func patternWeight(mode string) int {
+	switch mode {
+	case MatchExact:
+		return exactWeight
+	case MatchGlob:
+		return globWeight
+	case MatchRegex, MatchAuto:
+		return regexWeight
+	default:
+		return containsWeight
+	}
+}
+
+// scoreRule computes the specificity of a compiled rule
+func scoreRule(r *CompiledRule) {
+	add := func(condition string, weight int) {
+		r.Specificity += conditionWeight + weight
+		r.Conditions = append(r.Conditions, condition)
+	}
+
+	patterns := []struct {
+		name    string
+		pattern *Pattern
+	}{
+		{"class", r.Class},
+		{"title", r.Title},
+		{"not_class", r.NotClass},
+		{"not_title", r.NotTitle},
+		{"initial_class", r.InitialClass},
+		{"initial_title", r.InitialTitle},
+		{"workspace", r.Workspace},
+		{"monitor", r.Monitor},
+		{"layer", r.Layer},
+		{"app_id", r.AppID},
+		{"exe", r.Exe},
+		{"cmdline", r.Cmdline},
+		{"foreground_exe", r.ForegroundExe},
+		{"foreground_cmdline", r.ForegroundCmdline},
+	}
+
+	for _, field := range patterns {
+		if field.pattern != nil {
+			add(fmt.Sprintf("%s (%s)", field.name, field.pattern.Mode()), patternWeight(field.pattern.Mode()))
+		}
+	}
+
+	// Workspace IDs select a single workspace, "special" any special one
+	if r.Workspace == nil && r.Rule.Workspace != "" {
+		if r.Rule.Workspace == "special" {
+			add("workspace (special)", containsWeight)
+		} else {
+			add("workspace (id)", exactWeight)
+		}
+	}
+
+	flags := []struct {
+		name  string
+		value *bool
+	}{
+		{"xwayland", r.Rule.XWayland},
+		{"floating", r.Rule.Floating},
+		{"fullscreen", r.Rule.Fullscreen},
+		{"pinned", r.Rule.Pinned},
+		{"grouped", r.Rule.Grouped},
+	}
+
+	for _, flag := range flags {
+		if flag.value != nil {
+			add(flag.name, exactWeight)
+		}
+	}
+}
+
+// compileRule compiles the patterns of a single rule
+func compileRule(index int, rule *ClientRule) (*CompiledRule, error) {
+	compiled := &CompiledRule{Index: index, Rule: rule}
+
+	workspace := rule.Workspace
+	if _, err := strconv.Atoi(workspace); err == nil || workspace == "special" {
+		workspace = ""
+	}
+
+	fields := []struct {
+		name    string
+		pattern string
+		target  **Pattern
+	}{
+		{"class", rule.Class, &compiled.Class},
+		{"title", rule.Title, &compiled.Title},
+		{"not_class", rule.NotClass, &compiled.NotClass},
+		{"not_title", rule.NotTitle, &compiled.NotTitle},
+		{"initial_class", rule.InitialClass, &compiled.InitialClass},
+		{"initial_title", rule.InitialTitle, &compiled.InitialTitle},
+		{"workspace", workspace, &compiled.Workspace},
+		{"monitor", rule.Monitor, &compiled.Monitor},
+		{"layer", rule.Layer, &compiled.Layer},
+		{"app_id", rule.AppID, &compiled.AppID},
+		{"exe", rule.Exe, &compiled.Exe},
+		{"cmdline", rule.Cmdline, &compiled.Cmdline},
+		{"foreground_exe", rule.ForegroundExe, &compiled.ForegroundExe},
+		{"foreground_cmdline", rule.ForegroundCmdline, &compiled.ForegroundCmdline},
+	}
+
+	for _, field := range fields {
+		if field.pattern == "" {
+			continue
+		}
+
+		pattern, err := CompilePattern(rule.MatchMode(field.name), rule.IgnoreCase, field.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.name, err)
+		}
+
+		*field.target = pattern
+	}
+
+	scoreRule(compiled)
+
+	if !compiled.HasSubject() {
+		logger.Warningf("Client rule %d never matches: it needs a class, initial_class, workspace, monitor, layer, app_id, exe, cmdline or foreground condition", index)
+	}
+
+	return compiled, nil
+}
+
+// HasSubject checks if a rule has a condition identifying windows, as
+// opposed to only constraining them, such as a title or a flag. Rules
+// without one never match.
+func (r *CompiledRule) HasSubject() bool {
+	return r.Class != nil || r.InitialClass != nil ||
+		r.Rule.Workspace != "" || r.Monitor != nil || r.Layer != nil ||
+		r.AppID != nil || r.Exe != nil || r.Cmdline != nil ||
+		r.ForegroundExe != nil || r.ForegroundCmdline != nil
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompileRulesInvalidPattern(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  ClientRule
+		field string
+	}{
+		{"auto regex", ClientRule{Class: "foo(["}, "class"},
+		{"regex", ClientRule{Class: "firefox", Title: "(", MatchModes: map[string]string{"title": MatchRegex}}, "title"},
+		{"glob", ClientRule{Class: "[a-", Match: MatchGlob}, "class"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules := []ClientRule{{Class: "kitty"}, test.rule}
+
+			_, err := CompileRules(rules, EvaluationFirstMatch)
+			if err == nil {
+				t.Fatal("expected an error for an invalid pattern")
+			}
+
+			prefix := "client rule 1: " + test.field + ": invalid pattern"
+			if !strings.HasPrefix(err.Error(), prefix) {
+				t.Errorf("error %q does not start with %q", err, prefix)
+			}
+		})
+	}
+}
+
+func TestLoadConfigInvalidPattern(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `version: 2
+description: test
+input_methods:
+  english: keyboard-us
+default_input_method: english
+client_rules:
+  - class: kitty
+    input_method: english
+  - class: "foo(["
+    input_method: english
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfig(path)
+	if err == nil || !strings.HasPrefix(err.Error(), "client rule 1: class: invalid pattern") {
+		t.Fatalf("expected an invalid pattern error for rule 1, got %v", err)
+	}
+}
+
+func TestLoadDefaultConfig(t *testing.T) {
+	config, err := LoadConfig(filepath.Join("..", "..", "configs", "default.yaml"))
+	if err != nil {
+		t.Fatalf("default config fails to load: %v", err)
+	}
+
+	if len(config.Rules.Rules) != len(config.ClientRules) {
+		t.Errorf("compiled %d rules, want %d", len(config.Rules.Rules), len(config.ClientRules))
+	}
+
+	// Modes that change existing behavior are opt-in
+	if config.GameMode.Enabled {
+		t.Error("game mode is enabled in the default config")
+	}
+	if config.Fcitx5.SwitchEnglish {
+		t.Error("switch_english is enabled in the default config")
+	}
+}
+
+func TestCompileExtendedRules(t *testing.T) {
+	builtin := []ClientRule{{Class: "pinentry"}, {Class: "askpass", ForegroundExe: "ssh"}}
+	user := []ClientRule{{Class: "vault"}}
+
+	ruleSet, err := compileExtendedRules(builtin, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// User rules without process conditions keep the built-in flags
+	if !ruleSet.UsesProcess || !ruleSet.UsesForeground {
+		t.Errorf("process flags of the built-in rules were lost: %+v", ruleSet)
+	}
+
+	descriptions := make([]string, len(ruleSet.Rules))
+	for i := range ruleSet.Rules {
+		descriptions[i] = ruleSet.Rules[i].Describe()
+	}
+	if got := strings.Join(descriptions, ", "); got != "built-in rule 0, built-in rule 1, rule 0" {
+		t.Errorf("rules are %s", got)
+	}
+}
+
+func TestLoadConfigInvalidModeRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `version: 2
+description: test
+input_methods:
+  english: keyboard-us
+default_input_method: english
+client_rules:
+  - class: kitty
+    input_method: english
+password_safe:
+  enabled: true
+  client_rules:
+    - class: "vault(["
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfig(path)
+	if err == nil || !strings.HasPrefix(err.Error(), "password_safe: client rule 0: class: invalid pattern") {
+		t.Fatalf("expected an invalid pattern error for password_safe rule 0, got %v", err)
+	}
+}
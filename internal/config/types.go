@@ -2,23 +2,121 @@ package config
 
 // Config represents the application configuration
 type Config struct {
-	Version            int                `yaml:"version" json:"version"`
-	Description        string             `yaml:"description" json:"description"`
-	DefaultInputMethod string             `yaml:"default_input_method" json:"default_input_method"`
-	InputMethods       map[string]string  `yaml:"input_methods" json:"input_methods"`
-	ClientRules        []ClientRule       `yaml:"client_rules" json:"client_rules"`
-	Fcitx5             Fcitx5Config       `yaml:"fcitx5" json:"fcitx5"`
-	RimeSchemas        map[string]string  `yaml:"rime_schemas" json:"rime_schemas"`
-	Notifications      NotificationConfig `yaml:"notifications" json:"notifications"`
-	DisplayNames       map[string]string  `yaml:"display_names" json:"display_names"`
-	Icons              map[string]string  `yaml:"icons" json:"icons"`
+	Version            int                  `yaml:"version" json:"version"`
+	Description        string               `yaml:"description" json:"description"`
+	DefaultInputMethod string               `yaml:"default_input_method" json:"default_input_method"`
+	InputMethods       map[string]string    `yaml:"input_methods" json:"input_methods"`
+	Compositor         string               `yaml:"compositor" json:"compositor"`
+	Backend            string               `yaml:"backend" json:"backend"`
+	ClientRules        []ClientRule         `yaml:"client_rules" json:"client_rules"`
+	RuleEvaluation     string               `yaml:"rule_evaluation" json:"rule_evaluation"` // first_match (default) or specificity
+	Rules              *RuleSet             `yaml:"-" json:"-"`                             // compiled client rules, set by CompileConfigRules
+	PasswordSafe       PasswordSafeConfig   `yaml:"password_safe" json:"password_safe"`
+	GameMode           GameModeConfig       `yaml:"game_mode" json:"game_mode"`
+	Memory             MemoryConfig         `yaml:"memory" json:"memory"`
+	Process            ProcessConfig        `yaml:"process" json:"process"`
+	Fcitx5             Fcitx5Config         `yaml:"fcitx5" json:"fcitx5"`
+	IBus               IBusConfig           `yaml:"ibus" json:"ibus"`
+	KeyboardLayout     KeyboardLayoutConfig `yaml:"keyboard_layout" json:"keyboard_layout"`
+	RimeSchemas        map[string]string    `yaml:"rime_schemas" json:"rime_schemas"`
+	Notifications      NotificationConfig   `yaml:"notifications" json:"notifications"`
+	DisplayNames       map[string]string    `yaml:"display_names" json:"display_names"`
+	Icons              map[string]string    `yaml:"icons" json:"icons"`
 }
 
+// Rule actions
+const (
+	ActionSwitch          = "switch"           // switch to the rule's input method
+	ActionKeep            = "keep"             // leave the input method as is
+	ActionIgnore          = "ignore"           // act as if the window never gained focus
+	ActionRestorePrevious = "restore_previous" // switch to the previously focused window's input method
+)
+
 // ClientRule represents a client-specific input method rule
 type ClientRule struct {
 	Class       string `yaml:"class" json:"class"`
 	Title       string `yaml:"title" json:"title"`
+	Workspace   string `yaml:"workspace" json:"workspace"` // workspace ID, name or "special"
+	Monitor     string `yaml:"monitor" json:"monitor"`
+	Layer       string `yaml:"layer" json:"layer"` // layer surface namespace, for launchers; layer rules never match windows
 	InputMethod string `yaml:"input_method" json:"input_method"`
+	Action      string `yaml:"action" json:"action"` // switch (default), keep, ignore or restore_previous
+	FollowTitle bool   `yaml:"follow_title" json:"follow_title"`
+	Priority    int    `yaml:"priority" json:"priority"` // higher priorities are evaluated first, default 0
+
+	// Pattern matching; see MatchAuto and the other match modes
+	Match      string            `yaml:"match" json:"match"`             // match mode for all fields
+	MatchModes map[string]string `yaml:"match_modes" json:"match_modes"` // match mode per field, e.g. {title: regex}
+	IgnoreCase bool              `yaml:"ignore_case" json:"ignore_case"`
+	NotClass   string            `yaml:"not_class" json:"not_class"`
+	NotTitle   string            `yaml:"not_title" json:"not_title"`
+
+	// Window properties; unset conditions match any window
+	InitialClass string `yaml:"initial_class" json:"initial_class"`
+	InitialTitle string `yaml:"initial_title" json:"initial_title"`
+	XWayland     *bool  `yaml:"xwayland" json:"xwayland"`
+	Floating     *bool  `yaml:"floating" json:"floating"`
+	Fullscreen   *bool  `yaml:"fullscreen" json:"fullscreen"`
+	Pinned       *bool  `yaml:"pinned" json:"pinned"`
+	Grouped      *bool  `yaml:"grouped" json:"grouped"`
+
+	// Process of the window and, for terminals, the foreground job
+	AppID             string `yaml:"app_id" json:"app_id"` // Flatpak application ID or Snap name
+	Exe               string `yaml:"exe" json:"exe"`
+	Cmdline           string `yaml:"cmdline" json:"cmdline"`
+	ForegroundExe     string `yaml:"foreground_exe" json:"foreground_exe"`
+	ForegroundCmdline string `yaml:"foreground_cmdline" json:"foreground_cmdline"`
+}
+
+// Rule evaluation modes
+const (
+	EvaluationFirstMatch  = "first_match" // the first matching rule wins, by priority and then configuration order
+	EvaluationSpecificity = "specificity" // the most specific matching rule wins, by priority and then specificity
+)
+
+// Memory modes
+const (
+	MemoryModeRule   = "rule"   // rules decide on every focus change
+	MemoryModeWindow = "window" // rules decide on first focus, then the window's last input method is restored
+)
+
+// Learned input method precedence
+const (
+	PrecedenceRules   = "rules"   // client rules outrank learned input methods
+	PrecedenceLearned = "learned" // learned input methods outrank client rules
+)
+
+// MemoryConfig represents input method memory configuration
+type MemoryConfig struct {
+	Mode       string `yaml:"mode" json:"mode"`
+	Persist    bool   `yaml:"persist" json:"persist"`       // learn the last input method per window class across restarts
+	Precedence string `yaml:"precedence" json:"precedence"` // "rules" or "learned"
+}
+
+// PasswordSafeConfig represents password-safe mode configuration. Its
+// rules extend the built-in credential prompt rules and outrank client_rules.
+type PasswordSafeConfig struct {
+	Enabled     bool         `yaml:"enabled" json:"enabled"`
+	InputMethod string       `yaml:"input_method" json:"input_method"` // plain keyboard input method, default_input_method if empty
+	Deactivate  bool         `yaml:"deactivate" json:"deactivate"`     // also deactivate the input method engine
+	ClientRules []ClientRule `yaml:"client_rules" json:"client_rules"`
+	Rules       *RuleSet     `yaml:"-" json:"-"` // compiled built-in and user rules, set by CompileConfigRules
+}
+
+// GameModeConfig represents game mode configuration. While a game has focus,
+// automatic switching and notifications are suspended. Its rules extend the
+// built-in game rules.
+type GameModeConfig struct {
+	Enabled     bool         `yaml:"enabled" json:"enabled"`
+	Fullscreen  bool         `yaml:"fullscreen" json:"fullscreen"` // treat every fullscreen window as a game
+	Deactivate  bool         `yaml:"deactivate" json:"deactivate"` // deactivate the input method engine while a game has focus
+	ClientRules []ClientRule `yaml:"client_rules" json:"client_rules"`
+	Rules       *RuleSet     `yaml:"-" json:"-"` // compiled built-in and user rules, set by CompileConfigRules
+}
+
+// ProcessConfig represents process rule configuration
+type ProcessConfig struct {
+	PollInterval int `yaml:"poll_interval" json:"poll_interval"` // milliseconds, 0 disables polling
 }
 
 // Fcitx5Config represents fcitx5 configuration
@@ -26,6 +124,17 @@ type Fcitx5Config struct {
 	Enabled         bool   `yaml:"enabled" json:"enabled"`
 	RimeInputMethod string `yaml:"rime_input_method" json:"rime_input_method"`
 	RimeConfigDir   string `yaml:"rime_config_dir" json:"rime_config_dir"`
+	SwitchEnglish   bool   `yaml:"switch_english" json:"switch_english"` // switch english to its input_methods entry instead of deactivating fcitx5
+}
+
+// IBusConfig represents IBus configuration
+type IBusConfig struct {
+	Address string `yaml:"address" json:"address"`
+}
+
+// KeyboardLayoutConfig represents keyboard layout backend configuration
+type KeyboardLayoutConfig struct {
+	Devices []string `yaml:"devices" json:"devices"`
 }
 
 // NotificationConfig represents notification configuration
@@ -2,6 +2,7 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
 
 	"gopkg.in/yaml.v2"
@@ -26,9 +27,40 @@ func LoadConfig(filePath string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := CompileConfigRules(&config); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
+// CompileConfigRules compiles the client rules and the rules of the enabled
+// modes. Nothing is set if any rule is invalid.
+func CompileConfigRules(config *Config) error {
+	rules, err := CompileRules(config.ClientRules, config.RuleEvaluation)
+	if err != nil {
+		return err
+	}
+
+	var passwordRules, gameRules *RuleSet
+	if config.PasswordSafe.Enabled {
+		if passwordRules, err = CompilePasswordRules(config.PasswordSafe.ClientRules); err != nil {
+			return fmt.Errorf("password_safe: %w", err)
+		}
+	}
+
+	if config.GameMode.Enabled {
+		if gameRules, err = CompileGameRules(config.GameMode.ClientRules); err != nil {
+			return fmt.Errorf("game_mode: %w", err)
+		}
+	}
+
+	config.Rules = rules
+	config.PasswordSafe.Rules = passwordRules
+	config.GameMode.Rules = gameRules
+	return nil
+}
+
 func validateConfig(config *Config) error {
 	if config.Version <= 0 {
 		return errors.New("invalid configuration version")
@@ -45,5 +77,57 @@ func validateConfig(config *Config) error {
 	if len(config.ClientRules) == 0 {
 		return errors.New("client rules cannot be empty")
 	}
+	switch config.RuleEvaluation {
+	case "", EvaluationFirstMatch, EvaluationSpecificity:
+	default:
+		return fmt.Errorf("unknown rule evaluation %q (available: first_match, specificity)", config.RuleEvaluation)
+	}
+	for i := range config.ClientRules {
+		if err := validateMatchModes(&config.ClientRules[i]); err != nil {
+			return fmt.Errorf("client rule %d: %w", i, err)
+		}
+		if err := validateAction(&config.ClientRules[i]); err != nil {
+			return fmt.Errorf("client rule %d: %w", i, err)
+		}
+	}
+	for i := range config.PasswordSafe.ClientRules {
+		if err := validateMatchModes(&config.PasswordSafe.ClientRules[i]); err != nil {
+			return fmt.Errorf("password_safe: client rule %d: %w", i, err)
+		}
+	}
+	for i := range config.GameMode.ClientRules {
+		if err := validateMatchModes(&config.GameMode.ClientRules[i]); err != nil {
+			return fmt.Errorf("game_mode: client rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateMatchModes(rule *ClientRule) error {
+	if rule.Match != "" && !isMatchMode(rule.Match) {
+		return fmt.Errorf("unknown match mode %q (available: %v)", rule.Match, matchModes)
+	}
+	for field, mode := range rule.MatchModes {
+		if !isMatchMode(mode) {
+			return fmt.Errorf("unknown match mode %q for %s (available: %v)", mode, field, matchModes)
+		}
+	}
 	return nil
 }
+
+func validateAction(rule *ClientRule) error {
+	switch rule.Action {
+	case "", ActionSwitch, ActionKeep, ActionIgnore, ActionRestorePrevious:
+		return nil
+	}
+	return fmt.Errorf("unknown action %q (available: switch, keep, ignore, restore_previous)", rule.Action)
+}
+
+func isMatchMode(mode string) bool {
+	for _, candidate := range matchModes {
+		if mode == candidate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// requestTimeout bounds a single request on the control socket
+const requestTimeout = 5 * time.Second
+
+// Client sends requests to a running daemon's control socket
+type Client struct {
+	socketPath string
+}
+
+func NewClient(socketPath string) *Client {
+	if socketPath == "" {
+		socketPath = SocketPath()
+	}
+
+	return &Client{socketPath: socketPath}
+}
+
+// Send sends a request and waits for its response. Errors reported by the
+// daemon are returned as errors.
+func (c *Client) Send(request *Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", c.socketPath, requestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s (is hypr-input-switcher running?): %w", c.socketPath, err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var response Response
+	if err := json.Unmarshal(line, &response); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+
+	if !response.OK {
+		return &response, errors.New(response.Error)
+	}
+
+	return &response, nil
+}
@@ -0,0 +1,236 @@
+package control
+
+import (
+	"fmt"
+
+	"hypr-input-switcher/pkg/logger"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+// D-Bus service name, interface and object path
+const (
+	DBusName      = "io.github.HyprInputSwitcher1"
+	DBusInterface = "io.github.HyprInputSwitcher1"
+	DBusPath      = dbus.ObjectPath("/io/github/HyprInputSwitcher1")
+)
+
+// dbusIntrospection describes the exported object
+var dbusIntrospection = introspect.Node{
+	Name: string(DBusPath),
+	Interfaces: []introspect.Interface{
+		introspect.IntrospectData,
+		{
+			Name: "org.freedesktop.DBus.Properties",
+			Methods: []introspect.Method{
+				{Name: "Get", Args: []introspect.Arg{
+					{Name: "interface_name", Type: "s", Direction: "in"},
+					{Name: "property_name", Type: "s", Direction: "in"},
+					{Name: "value", Type: "v", Direction: "out"},
+				}},
+				{Name: "GetAll", Args: []introspect.Arg{
+					{Name: "interface_name", Type: "s", Direction: "in"},
+					{Name: "properties", Type: "a{sv}", Direction: "out"},
+				}},
+				{Name: "Set", Args: []introspect.Arg{
+					{Name: "interface_name", Type: "s", Direction: "in"},
+					{Name: "property_name", Type: "s", Direction: "in"},
+					{Name: "value", Type: "v", Direction: "in"},
+				}},
+			},
+			Signals: []introspect.Signal{
+				{Name: "PropertiesChanged", Args: []introspect.Arg{
+					{Name: "interface_name", Type: "s"},
+					{Name: "changed_properties", Type: "a{sv}"},
+					{Name: "invalidated_properties", Type: "as"},
+				}},
+			},
+		},
+		{
+			Name: DBusInterface,
+			Methods: []introspect.Method{
+				{Name: "Switch", Args: []introspect.Arg{{Name: "input_method", Type: "s", Direction: "in"}}},
+				{Name: "Pause"},
+				{Name: "Resume"},
+				{Name: "Reload"},
+			},
+			Properties: []introspect.Property{
+				{Name: "CurrentInputMethod", Type: "s", Access: "read"},
+				{Name: "ActiveRule", Type: "a{sv}", Access: "read"},
+			},
+			Signals: []introspect.Signal{
+				{Name: "InputMethodChanged", Args: []introspect.Arg{
+					{Name: "input_method", Type: "s"},
+					{Name: "class", Type: "s"},
+					{Name: "title", Type: "s"},
+					{Name: "reason", Type: "s"},
+				}},
+			},
+		},
+	},
+}
+
+// DBusService exports the daemon on the session bus
+type DBusService struct {
+	handler Handler
+	conn    *dbus.Conn
+}
+
+func NewDBusService(handler Handler) *DBusService {
+	return &DBusService{handler: handler}
+}
+
+// Start connects to the session bus, exports the service object and claims
+// the service name
+func (s *DBusService) Start() error {
+	// A private connection, as the shared one is closed by its other users
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+
+	exports := []struct {
+		value interface{}
+		iface string
+	}{
+		{&dbusMethods{handler: s.handler}, DBusInterface},
+		{&dbusProperties{handler: s.handler}, "org.freedesktop.DBus.Properties"},
+		{introspect.NewIntrospectable(&dbusIntrospection), "org.freedesktop.DBus.Introspectable"},
+	}
+
+	for _, export := range exports {
+		if err := conn.Export(export.value, DBusPath, export.iface); err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to export %s: %w", export.iface, err)
+		}
+	}
+
+	reply, err := conn.RequestName(DBusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to request name %s: %w", DBusName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return fmt.Errorf("name %s is owned by another instance", DBusName)
+	}
+
+	s.conn = conn
+
+	logger.Debugf("D-Bus service exported as %s", DBusName)
+	return nil
+}
+
+// Close releases the service name and disconnects
+func (s *DBusService) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	return s.conn.Close()
+}
+
+// EmitInputMethodChanged emits InputMethodChanged, and PropertiesChanged for
+// the properties depending on it
+func (s *DBusService) EmitInputMethodChanged(inputMethod, class, title, reason string) {
+	if s.conn == nil {
+		return
+	}
+
+	if err := s.conn.Emit(DBusPath, DBusInterface+".InputMethodChanged", inputMethod, class, title, reason); err != nil {
+		logger.Debugf("Failed to emit InputMethodChanged: %v", err)
+	}
+
+	changed := map[string]dbus.Variant{"CurrentInputMethod": dbus.MakeVariant(inputMethod)}
+	if err := s.conn.Emit(DBusPath, "org.freedesktop.DBus.Properties.PropertiesChanged", DBusInterface, changed, []string{"ActiveRule"}); err != nil {
+		logger.Debugf("Failed to emit PropertiesChanged: %v", err)
+	}
+}
+
+// dbusMethods holds the methods of the service interface
+type dbusMethods struct {
+	handler Handler
+}
+
+// Switch switches to an input method
+func (m *dbusMethods) Switch(inputMethod string) *dbus.Error {
+	return dbusError(m.handler.Switch(inputMethod))
+}
+
+// Pause pauses automatic switching
+func (m *dbusMethods) Pause() *dbus.Error {
+	m.handler.Pause()
+	return nil
+}
+
+// Resume resumes automatic switching
+func (m *dbusMethods) Resume() *dbus.Error {
+	return dbusError(m.handler.Resume())
+}
+
+// Reload reloads the configuration file
+func (m *dbusMethods) Reload() *dbus.Error {
+	return dbusError(m.handler.Reload())
+}
+
+// dbusProperties implements org.freedesktop.DBus.Properties. Properties are
+// read from the handler on every call, so they are never stale.
+type dbusProperties struct {
+	handler Handler
+}
+
+// Get returns a single property
+func (p *dbusProperties) Get(iface, property string) (dbus.Variant, *dbus.Error) {
+	properties, dbusErr := p.GetAll(iface)
+	if dbusErr != nil {
+		return dbus.Variant{}, dbusErr
+	}
+
+	value, exists := properties[property]
+	if !exists {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{fmt.Sprintf("unknown property %s", property)})
+	}
+
+	return value, nil
+}
+
+// GetAll returns all properties of the service interface
+func (p *dbusProperties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	if iface != DBusInterface {
+		return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []interface{}{fmt.Sprintf("unknown interface %s", iface)})
+	}
+
+	return map[string]dbus.Variant{
+		"CurrentInputMethod": dbus.MakeVariant(p.handler.CurrentInputMethod()),
+		"ActiveRule":         dbus.MakeVariant(p.activeRule()),
+	}, nil
+}
+
+// Set rejects writes, all properties are read-only
+func (p *dbusProperties) Set(iface, property string, value dbus.Variant) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{fmt.Sprintf("property %s is read-only", property)})
+}
+
+// activeRule returns the rule matching the focused window, as reported by
+// the match request
+func (p *dbusProperties) activeRule() map[string]dbus.Variant {
+	activeRule := make(map[string]dbus.Variant)
+
+	rule, _ := p.handler.Match()["rule"].(map[string]interface{})
+	for key, value := range rule {
+		if value != nil {
+			activeRule[key] = dbus.MakeVariant(value)
+		}
+	}
+
+	return activeRule
+}
+
+// dbusError converts an error into a D-Bus error reply
+func dbusError(err error) *dbus.Error {
+	if err == nil {
+		return nil
+	}
+	return dbus.MakeFailedError(err)
+}
@@ -0,0 +1,196 @@
+package control
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"hypr-input-switcher/internal/testutil"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeHandler records control requests
+type fakeHandler struct {
+	mutex       sync.Mutex
+	inputMethod string
+	paused      bool
+}
+
+func (h *fakeHandler) CurrentInputMethod() string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.inputMethod
+}
+
+func (h *fakeHandler) Status() map[string]interface{} {
+	return map[string]interface{}{"current_im": h.CurrentInputMethod()}
+}
+
+func (h *fakeHandler) Switch(inputMethod string) error {
+	if inputMethod != "english" && inputMethod != "chinese" {
+		return fmt.Errorf("unknown input method %s", inputMethod)
+	}
+
+	h.mutex.Lock()
+	h.inputMethod = inputMethod
+	h.mutex.Unlock()
+	return nil
+}
+
+func (h *fakeHandler) Reload() error {
+	return nil
+}
+
+func (h *fakeHandler) Pause() {
+	h.mutex.Lock()
+	h.paused = true
+	h.mutex.Unlock()
+}
+
+func (h *fakeHandler) Paused() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.paused
+}
+
+func (h *fakeHandler) Resume() error {
+	h.mutex.Lock()
+	h.paused = false
+	h.mutex.Unlock()
+	return nil
+}
+
+func (h *fakeHandler) Match() map[string]interface{} {
+	return map[string]interface{}{
+		"rule": map[string]interface{}{
+			"index":        1,
+			"input_method": "chinese",
+			"title":        nil,
+		},
+	}
+}
+
+// startTestService exports the service on a private bus and returns a
+// client connection to the same bus
+func startTestService(t *testing.T, handler Handler) (*DBusService, *dbus.Conn) {
+	t.Helper()
+
+	address := testutil.StartDBus(t)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", address)
+
+	service := NewDBusService(handler)
+	if err := service.Start(); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	t.Cleanup(func() { service.Close() })
+
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("failed to connect to test bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return service, conn
+}
+
+func TestDBusProperties(t *testing.T) {
+	_, conn := startTestService(t, &fakeHandler{inputMethod: "english"})
+	obj := conn.Object(DBusName, DBusPath)
+
+	value, err := obj.GetProperty(DBusInterface + ".CurrentInputMethod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.Value() != "english" {
+		t.Errorf("CurrentInputMethod = %v, want english", value.Value())
+	}
+
+	var properties map[string]dbus.Variant
+	if err := obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, DBusInterface).Store(&properties); err != nil {
+		t.Fatal(err)
+	}
+
+	var rule map[string]dbus.Variant
+	if err := properties["ActiveRule"].Store(&rule); err != nil {
+		t.Fatalf("failed to read ActiveRule: %v", err)
+	}
+	if rule["input_method"].Value() != "chinese" || rule["index"].Value() != int32(1) {
+		t.Errorf("unexpected ActiveRule: %v", rule)
+	}
+	if _, exists := rule["title"]; exists {
+		t.Error("ActiveRule includes a nil field")
+	}
+
+	if _, err := obj.GetProperty(DBusInterface + ".Missing"); !isDBusError(err, "org.freedesktop.DBus.Error.UnknownProperty") {
+		t.Errorf("Get of a missing property returned %v", err)
+	}
+
+	if err := obj.SetProperty(DBusInterface+".CurrentInputMethod", dbus.MakeVariant("chinese")); !isDBusError(err, "org.freedesktop.DBus.Error.PropertyReadOnly") {
+		t.Errorf("Set returned %v, want PropertyReadOnly", err)
+	}
+}
+
+func TestDBusMethods(t *testing.T) {
+	handler := &fakeHandler{inputMethod: "english"}
+	_, conn := startTestService(t, handler)
+	obj := conn.Object(DBusName, DBusPath)
+
+	if err := obj.Call(DBusInterface+".Switch", 0, "chinese").Err; err != nil {
+		t.Fatalf("Switch failed: %v", err)
+	}
+	if current := handler.CurrentInputMethod(); current != "chinese" {
+		t.Errorf("handler input method = %s, want chinese", current)
+	}
+
+	if err := obj.Call(DBusInterface+".Switch", 0, "klingon").Err; !isDBusError(err, "org.freedesktop.DBus.Error.Failed") {
+		t.Errorf("Switch to an unknown input method returned %v", err)
+	}
+
+	if err := obj.Call(DBusInterface+".Pause", 0).Err; err != nil || !handler.Paused() {
+		t.Errorf("Pause failed: %v", err)
+	}
+	if err := obj.Call(DBusInterface+".Resume", 0).Err; err != nil || handler.Paused() {
+		t.Errorf("Resume failed: %v", err)
+	}
+}
+
+func TestDBusInputMethodChanged(t *testing.T) {
+	service, conn := startTestService(t, &fakeHandler{})
+
+	if err := conn.AddMatchSignal(dbus.WithMatchObjectPath(DBusPath), dbus.WithMatchInterface(DBusInterface)); err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+
+	service.EmitInputMethodChanged("chinese", "firefox", "GitHub", "rule")
+
+	select {
+	case signal := <-signals:
+		if signal.Name != DBusInterface+".InputMethodChanged" {
+			t.Fatalf("got signal %s", signal.Name)
+		}
+		want := []interface{}{"chinese", "firefox", "GitHub", "rule"}
+		if fmt.Sprint(signal.Body) != fmt.Sprint(want) {
+			t.Errorf("signal arguments = %v, want %v", signal.Body, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for InputMethodChanged")
+	}
+}
+
+func TestDBusNameTaken(t *testing.T) {
+	startTestService(t, &fakeHandler{})
+
+	if err := NewDBusService(&fakeHandler{}).Start(); err == nil {
+		t.Error("second service claimed an owned name")
+	}
+}
+
+// isDBusError checks if err is a D-Bus error reply with the given name
+func isDBusError(err error, name string) bool {
+	dbusErr, ok := err.(dbus.Error)
+	return ok && dbusErr.Name == name
+}
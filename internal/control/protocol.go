@@ -0,0 +1,53 @@
+package control
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Commands understood by the control socket
+const (
+	CommandStatus = "status" // switcher and notification status
+	CommandSwitch = "switch" // switch to Request.InputMethod
+	CommandReload = "reload" // reload the configuration file
+	CommandPause  = "pause"  // suspend automatic switching
+	CommandResume = "resume" // resume automatic switching
+	CommandMatch  = "match"  // the focused window and the rule matching it
+)
+
+// Request is a single request on the control socket. Requests and responses
+// are JSON objects, one per line.
+type Request struct {
+	Command     string `json:"command"`
+	InputMethod string `json:"input_method,omitempty"` // for switch
+}
+
+// Response answers a Request
+type Response struct {
+	OK    bool                   `json:"ok"`
+	Error string                 `json:"error,omitempty"`
+	Data  map[string]interface{} `json:"data,omitempty"`
+}
+
+// Handler carries out control requests for the running daemon
+type Handler interface {
+	CurrentInputMethod() string
+	Status() map[string]interface{}
+	Switch(inputMethod string) error
+	Reload() error
+	Pause()
+	Resume() error
+	Match() map[string]interface{}
+}
+
+// SocketPath returns the control socket path under $XDG_RUNTIME_DIR
+func SocketPath() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		// Fallback to a per-user directory in /tmp
+		return filepath.Join(os.TempDir(), fmt.Sprintf("hypr-input-switcher-%d", os.Getuid()), "control.sock")
+	}
+
+	return filepath.Join(runtimeDir, "hypr-input-switcher", "control.sock")
+}
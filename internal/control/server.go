@@ -0,0 +1,171 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"hypr-input-switcher/pkg/logger"
+)
+
+// idleTimeout closes control connections that stop sending requests
+const idleTimeout = 30 * time.Second
+
+// Server answers requests on the control socket
+type Server struct {
+	path        string
+	handler     Handler
+	idleTimeout time.Duration
+	listener    net.Listener
+	wait        sync.WaitGroup
+}
+
+func NewServer(path string, handler Handler) *Server {
+	return &Server{
+		path:        path,
+		handler:     handler,
+		idleTimeout: idleTimeout,
+	}
+}
+
+// Path returns the control socket path
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Start listens on the control socket and serves requests in the background.
+// A socket left behind by a crashed instance is replaced, one still answering
+// is not.
+func (s *Server) Start() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+
+	if _, err := os.Stat(s.path); err == nil {
+		if conn, err := net.DialTimeout("unix", s.path, time.Second); err == nil {
+			conn.Close()
+			return fmt.Errorf("control socket %s is in use by another instance", s.path)
+		}
+
+		logger.Debugf("Removing stale control socket: %s", s.path)
+		if err := os.Remove(s.path); err != nil {
+			return fmt.Errorf("failed to remove stale control socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+
+	// Only the user may control the daemon
+	if err := os.Chmod(s.path, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict control socket permissions: %w", err)
+	}
+
+	s.listener = listener
+
+	s.wait.Add(1)
+	go s.acceptLoop()
+
+	logger.Debugf("Control socket listening on: %s", s.path)
+	return nil
+}
+
+// Close stops serving and removes the socket
+func (s *Server) Close() error {
+	if s.listener == nil {
+		return nil
+	}
+
+	err := s.listener.Close()
+	s.wait.Wait()
+	return err
+}
+
+// acceptLoop accepts connections until the listener is closed
+func (s *Server) acceptLoop() {
+	defer s.wait.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Warningf("Control socket accept failed: %v", err)
+			}
+			return
+		}
+
+		go s.serve(conn)
+	}
+}
+
+// serve answers the requests of a single connection
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(s.idleTimeout))
+		if !scanner.Scan() {
+			return
+		}
+
+		var request Request
+		response := &Response{}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			response.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			response = s.handle(&request)
+		}
+
+		if err := encoder.Encode(response); err != nil {
+			logger.Debugf("Failed to write control response: %v", err)
+			return
+		}
+	}
+}
+
+// handle carries out a single request
+func (s *Server) handle(request *Request) *Response {
+	logger.Debugf("Control request: %s", request.Command)
+
+	var data map[string]interface{}
+	var err error
+
+	switch request.Command {
+	case CommandStatus:
+		data = s.handler.Status()
+	case CommandSwitch:
+		if request.InputMethod == "" {
+			err = errors.New("switch needs an input_method")
+		} else {
+			err = s.handler.Switch(request.InputMethod)
+		}
+	case CommandReload:
+		err = s.handler.Reload()
+	case CommandPause:
+		s.handler.Pause()
+	case CommandResume:
+		err = s.handler.Resume()
+	case CommandMatch:
+		data = s.handler.Match()
+	default:
+		err = fmt.Errorf("unknown command %q", request.Command)
+	}
+
+	if err != nil {
+		return &Response{Error: err.Error()}
+	}
+
+	return &Response{OK: true, Data: data}
+}
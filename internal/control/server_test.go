@@ -0,0 +1,234 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestServer serves handler on a socket in a temporary directory
+func startTestServer(t *testing.T, handler Handler) *Server {
+	t.Helper()
+
+	server := NewServer(filepath.Join(t.TempDir(), "control", "control.sock"), handler)
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	return server
+}
+
+func TestServerSocketPermissions(t *testing.T) {
+	server := startTestServer(t, &fakeHandler{})
+
+	info, err := os.Stat(server.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("socket has permissions %v, want 0600", info.Mode().Perm())
+	}
+
+	dirInfo, err := os.Stat(filepath.Dir(server.Path()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirInfo.Mode().Perm() != 0700 {
+		t.Errorf("socket directory has permissions %v, want 0700", dirInfo.Mode().Perm())
+	}
+}
+
+func TestServerRoundTrip(t *testing.T) {
+	handler := &fakeHandler{inputMethod: "english"}
+	client := NewClient(startTestServer(t, handler).Path())
+
+	response, err := client.Send(&Request{Command: CommandStatus})
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if !response.OK || response.Data["current_im"] != "english" {
+		t.Errorf("unexpected status response: %+v", response)
+	}
+
+	if _, err := client.Send(&Request{Command: CommandSwitch, InputMethod: "chinese"}); err != nil {
+		t.Fatalf("switch failed: %v", err)
+	}
+	if handler.CurrentInputMethod() != "chinese" {
+		t.Errorf("switch did not reach the handler, current is %s", handler.CurrentInputMethod())
+	}
+
+	if _, err := client.Send(&Request{Command: CommandPause}); err != nil {
+		t.Fatalf("pause failed: %v", err)
+	}
+	if !handler.Paused() {
+		t.Error("pause did not reach the handler")
+	}
+
+	if _, err := client.Send(&Request{Command: CommandResume}); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if handler.Paused() {
+		t.Error("resume did not reach the handler")
+	}
+
+	response, err = client.Send(&Request{Command: CommandMatch})
+	if err != nil {
+		t.Fatalf("match failed: %v", err)
+	}
+	rule, ok := response.Data["rule"].(map[string]interface{})
+	if !ok || rule["input_method"] != "chinese" {
+		t.Errorf("unexpected match response: %+v", response)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	client := NewClient(startTestServer(t, &fakeHandler{}).Path())
+
+	tests := []struct {
+		name    string
+		request Request
+		want    string
+	}{
+		{"unknown command", Request{Command: "frobnicate"}, `unknown command "frobnicate"`},
+		{"switch without input method", Request{Command: CommandSwitch}, "switch needs an input_method"},
+		{"handler error", Request{Command: CommandSwitch, InputMethod: "klingon"}, "unknown input method klingon"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := client.Send(&test.request)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("got error %v, want %q", err, test.want)
+			}
+			if response == nil || response.OK || response.Error != test.want {
+				t.Errorf("unexpected response: %+v", response)
+			}
+		})
+	}
+}
+
+func TestServerFraming(t *testing.T) {
+	server := startTestServer(t, &fakeHandler{inputMethod: "english"})
+
+	conn, err := net.Dial("unix", server.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Several requests on one connection, including a malformed one, are
+	// answered in order, one line each
+	requests := `{"command":"status"}` + "\n" +
+		`not json` + "\n" +
+		`{"command":"switch","input_method":"chinese"}` + "\n" +
+		`{"command":"status"}` + "\n"
+	if _, err := io.WriteString(conn, requests); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+	var responses []Response
+	for i := 0; i < 4; i++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("failed to read response %d: %v", i, err)
+		}
+
+		var response Response
+		if err := json.Unmarshal(line, &response); err != nil {
+			t.Fatalf("response %d is not JSON: %q", i, line)
+		}
+		responses = append(responses, response)
+	}
+
+	if !responses[0].OK || responses[0].Data["current_im"] != "english" {
+		t.Errorf("unexpected first response: %+v", responses[0])
+	}
+	if responses[1].OK || !strings.HasPrefix(responses[1].Error, "invalid request") {
+		t.Errorf("unexpected response to malformed request: %+v", responses[1])
+	}
+	if !responses[2].OK {
+		t.Errorf("unexpected switch response: %+v", responses[2])
+	}
+	if !responses[3].OK || responses[3].Data["current_im"] != "chinese" {
+		t.Errorf("unexpected last response: %+v", responses[3])
+	}
+}
+
+func TestServerIdleTimeout(t *testing.T) {
+	server := NewServer(filepath.Join(t.TempDir(), "control.sock"), &fakeHandler{})
+	server.idleTimeout = 100 * time.Millisecond
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	conn, err := net.Dial("unix", server.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// The server hangs up on a connection that sends nothing
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("got %v, want the server to close the idle connection", err)
+	}
+}
+
+func TestServerReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+
+	// A socket file nobody listens on, as left behind by a crash
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	server := NewServer(path, &fakeHandler{inputMethod: "english"})
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to replace stale socket: %v", err)
+	}
+	defer server.Close()
+
+	if _, err := NewClient(path).Send(&Request{Command: CommandStatus}); err != nil {
+		t.Errorf("status failed: %v", err)
+	}
+}
+
+func TestServerRefusesSocketInUse(t *testing.T) {
+	server := startTestServer(t, &fakeHandler{})
+
+	if err := NewServer(server.Path(), &fakeHandler{}).Start(); err == nil {
+		t.Fatal("second server started on a socket in use")
+	}
+
+	// The running server keeps its socket
+	if _, err := NewClient(server.Path()).Send(&Request{Command: CommandStatus}); err != nil {
+		t.Errorf("status failed: %v", err)
+	}
+}
+
+func TestServerCloseRemovesSocket(t *testing.T) {
+	server := NewServer(filepath.Join(t.TempDir(), "control.sock"), &fakeHandler{})
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(server.Path()); !os.IsNotExist(err) {
+		t.Errorf("socket still exists after Close: %v", err)
+	}
+}
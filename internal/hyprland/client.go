@@ -0,0 +1,219 @@
+package hyprland
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"hypr-input-switcher/pkg/logger"
+)
+
+// requestTimeout bounds a single request on the request socket
+const requestTimeout = 2 * time.Second
+
+// Client sends requests to Hyprland's request socket (.socket.sock), the
+// socket hyprctl itself talks to. If the socket cannot be reached, requests
+// fall back to exec'ing hyprctl.
+type Client struct {
+	mutex      sync.Mutex // guards socketPath, clients are shared by goroutines
+	socketPath string
+}
+
+func NewClient() *Client {
+	return &Client{
+		socketPath: RequestSocketPath(),
+	}
+}
+
+// IsAvailable checks if Hyprland answers requests
+func (c *Client) IsAvailable() bool {
+	_, err := c.Request("version")
+	return err == nil
+}
+
+// Request sends a raw command such as "j/activewindow" and returns the reply
+func (c *Client) Request(command string) ([]byte, error) {
+	reply, err := c.requestViaSocket(command)
+	if err == nil {
+		return reply, nil
+	}
+
+	logger.Debugf("Hyprland socket request %q failed, falling back to hyprctl: %v", command, err)
+	return c.requestViaHyprctl(command)
+}
+
+// requestViaSocket sends a command over the request socket
+func (c *Client) requestViaSocket(command string) ([]byte, error) {
+	socketPath := c.requestSocketPath()
+	if socketPath == "" {
+		return nil, fmt.Errorf("hyprland request socket not found")
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, requestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(requestTimeout)); err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write([]byte(command)); err != nil {
+		return nil, err
+	}
+
+	// Hyprland closes the connection after writing the full reply
+	return io.ReadAll(conn)
+}
+
+// requestSocketPath returns the request socket path, looking it up again
+// if it was not found yet, as Hyprland may have started after the client
+func (c *Client) requestSocketPath() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.socketPath == "" {
+		c.socketPath = RequestSocketPath()
+	}
+	return c.socketPath
+}
+
+// requestViaHyprctl runs the equivalent hyprctl command
+func (c *Client) requestViaHyprctl(command string) ([]byte, error) {
+	var args []string
+
+	if batch, found := strings.CutPrefix(command, "[[BATCH]]"); found {
+		args = []string{"--batch", batch}
+	} else {
+		if rest, found := strings.CutPrefix(command, "j/"); found {
+			args = append(args, "-j")
+			command = rest
+		}
+		args = append(args, strings.Fields(command)...)
+	}
+
+	output, err := exec.Command("hyprctl", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("hyprctl command failed: %w", err)
+	}
+
+	return output, nil
+}
+
+// RequestJSON sends a command with JSON output and decodes the reply into v
+func (c *Client) RequestJSON(command string, v interface{}) error {
+	reply, err := c.Request("j/" + command)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(reply, v); err != nil {
+		return fmt.Errorf("failed to parse %s reply: %w", command, err)
+	}
+
+	return nil
+}
+
+// ActiveWindow decodes the active window into v
+func (c *Client) ActiveWindow(v interface{}) error {
+	return c.RequestJSON("activewindow", v)
+}
+
+// Clients decodes the list of all windows into v
+func (c *Client) Clients(v interface{}) error {
+	return c.RequestJSON("clients", v)
+}
+
+// Monitors decodes the list of monitors into v
+func (c *Client) Monitors(v interface{}) error {
+	return c.RequestJSON("monitors", v)
+}
+
+// Devices decodes the list of input devices into v
+func (c *Client) Devices(v interface{}) error {
+	return c.RequestJSON("devices", v)
+}
+
+// Notify shows a Hyprland notification
+func (c *Client) Notify(icon int, durationMs int, color string, message string) error {
+	return c.command(fmt.Sprintf("notify %d %d %s %s", icon, durationMs, color, message))
+}
+
+// Dispatch runs a dispatcher, e.g. Dispatch("workspace", "2")
+func (c *Client) Dispatch(args ...string) error {
+	return c.command("dispatch " + strings.Join(args, " "))
+}
+
+// Batch sends several commands in a single request
+func (c *Client) Batch(commands ...string) error {
+	reply, err := c.Request("[[BATCH]]" + strings.Join(commands, ";"))
+	if err != nil {
+		return err
+	}
+
+	return checkReply(reply)
+}
+
+// command sends a command whose reply is "ok" on success
+func (c *Client) command(command string) error {
+	reply, err := c.Request(command)
+	if err != nil {
+		return err
+	}
+
+	return checkReply(reply)
+}
+
+// checkReply converts a non-"ok" reply into an error. Batch replies hold
+// one reply per command.
+func checkReply(reply []byte) error {
+	for _, line := range strings.Split(string(reply), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && line != "ok" {
+			return fmt.Errorf("hyprland: %s", line)
+		}
+	}
+	return nil
+}
+
+// RequestSocketPath returns the path of the request socket, or an empty
+// string if no Hyprland instance is found
+func RequestSocketPath() string {
+	return findSocket(".socket.sock")
+}
+
+// findSocket looks for a socket in the Hyprland instance directory
+func findSocket(name string) string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = "/tmp"
+	}
+
+	// Try the current instance first
+	if signature := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE"); signature != "" {
+		for _, baseDir := range []string{filepath.Join(runtimeDir, "hypr"), "/tmp/hypr"} {
+			socketPath := filepath.Join(baseDir, signature, name)
+			if _, err := os.Stat(socketPath); err == nil {
+				return socketPath
+			}
+		}
+	}
+
+	// Fallback to the first instance found
+	for _, baseDir := range []string{filepath.Join(runtimeDir, "hypr"), "/tmp/hypr"} {
+		matches, err := filepath.Glob(filepath.Join(baseDir, "*", name))
+		if err == nil && len(matches) > 0 {
+			return matches[0]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,70 @@
+package hyprland
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// startFakeHyprland answers every request on a request socket with "ok"
+func startFakeHyprland(t *testing.T, runtimeDir string) {
+	t.Helper()
+
+	instanceDir := filepath.Join(runtimeDir, "hypr", "test")
+	if err := os.MkdirAll(instanceDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("unix", filepath.Join(instanceDir, ".socket.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Read(make([]byte, 4096))
+			conn.Write([]byte("ok"))
+			conn.Close()
+		}
+	}()
+}
+
+func TestClientFindsSocketStartedLater(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "test")
+
+	// Hyprland is not running yet when the client is created
+	client := NewClient()
+	if _, err := client.requestViaSocket("version"); err == nil {
+		t.Fatal("request succeeded without a socket")
+	}
+
+	startFakeHyprland(t, runtimeDir)
+
+	// Concurrent first requests resolve the socket path once, without racing
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.requestViaSocket("version"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("request failed: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package inputmethod
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"hypr-input-switcher/internal/config"
+	"hypr-input-switcher/pkg/logger"
+)
+
+// Backend is an input method engine the switcher can drive
+type Backend interface {
+	// Name returns the registry name of the backend
+	Name() string
+
+	// Detect reports whether the engine appears to be running on this system
+	Detect() bool
+
+	// IsAvailable checks if the backend is ready to switch input methods
+	IsAvailable() bool
+
+	// GetCurrent returns the logical name of the active input method,
+	// or "unknown" if it cannot be determined
+	GetCurrent() string
+
+	// Switch activates the given logical input method
+	Switch(inputMethod string) error
+
+	// ListInputMethods returns the logical input methods this backend can switch to
+	ListInputMethods() []string
+}
+
+// EventHandler is implemented by backends that track their state from
+// Hyprland socket2 events
+type EventHandler interface {
+	HandleEvent(eventType, eventData string)
+}
+
+// Deactivator is implemented by backends that can turn the input method
+// engine off entirely, leaving only direct keyboard input
+type Deactivator interface {
+	Deactivate() error
+}
+
+// ActiveChecker is implemented by backends where one input method has
+// several spellings, so GetCurrent may not return the switched-to name
+type ActiveChecker interface {
+	IsActive(inputMethod string) bool
+}
+
+// StatusReporter is implemented by backends with engine specific status,
+// which is merged into the switcher status
+type StatusReporter interface {
+	Status() map[string]interface{}
+}
+
+// BackendFactory creates a backend from the application configuration
+type BackendFactory func(cfg *config.Config) Backend
+
+type backendEntry struct {
+	name    string
+	factory BackendFactory
+}
+
+// backendRegistry holds the known backends in auto-detection order
+var backendRegistry = []backendEntry{
+	{name: "fcitx5", factory: newFcitx5Backend},
+	{name: "fcitx4", factory: newFcitx4Backend},
+	{name: "ibus", factory: newIBusBackend},
+	{name: "layout", factory: newLayoutBackend},
+}
+
+// RegisterBackend adds a backend to the registry. Registering an existing
+// name replaces its factory while keeping its detection order.
+func RegisterBackend(name string, factory BackendFactory) {
+	for i, entry := range backendRegistry {
+		if entry.name == name {
+			backendRegistry[i].factory = factory
+			return
+		}
+	}
+
+	backendRegistry = append(backendRegistry, backendEntry{name: name, factory: factory})
+}
+
+// AvailableBackends returns the names of all registered backends
+func AvailableBackends() []string {
+	names := make([]string, 0, len(backendRegistry))
+	for _, entry := range backendRegistry {
+		names = append(names, entry.name)
+	}
+	return names
+}
+
+// NewBackend creates the backend selected by the configuration. An empty
+// or "auto" selection picks the first registered backend that detects
+// its engine; "none" disables input method switching.
+func NewBackend(cfg *config.Config) (Backend, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.Backend))
+
+	switch name {
+	case "none":
+		return nil, nil
+	case "", "auto":
+		for _, entry := range backendRegistry {
+			backend := entry.factory(cfg)
+			if backend == nil {
+				continue
+			}
+
+			if backend.Detect() {
+				logger.Debugf("Auto-detected input method backend: %s", entry.name)
+				return backend, nil
+			}
+			logger.Tracef("Input method backend %s not detected", entry.name)
+		}
+		return nil, fmt.Errorf("no input method backend detected (tried: %s)", strings.Join(AvailableBackends(), ", "))
+	}
+
+	for _, entry := range backendRegistry {
+		if entry.name != name {
+			continue
+		}
+
+		backend := entry.factory(cfg)
+		if backend == nil {
+			return nil, fmt.Errorf("input method backend %s is disabled in configuration", name)
+		}
+		return backend, nil
+	}
+
+	return nil, fmt.Errorf("unknown input method backend: %s (available: %s)", name, strings.Join(AvailableBackends(), ", "))
+}
+
+// lookupInputMethod finds the logical input method mapped to an engine
+// name. Keys are checked in sorted order so the result is deterministic
+// when several logical names share one engine.
+func lookupInputMethod(inputMethods map[string]string, engineName string) (string, bool) {
+	for _, inputMethod := range sortedKeys(inputMethods) {
+		if inputMethods[inputMethod] == engineName {
+			return inputMethod, true
+		}
+	}
+	return "", false
+}
+
+// sortedKeys returns the keys of a string map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,22 @@
+package inputmethod
+
+// Reasons for an input method change
+const (
+	ReasonRule             = "rule"              // the matching client rule asked for it
+	ReasonDefault          = "default"           // no rule matched
+	ReasonLearned          = "learned"           // learned for the window class
+	ReasonWindowMemory     = "window_memory"     // the window's own last input method
+	ReasonRestorePrevious  = "restore_previous"  // a restore_previous rule matched
+	ReasonCredentialPrompt = "credential_prompt" // password-safe mode
+	ReasonLauncher         = "launcher"          // a layer rule matched a launcher
+	ReasonRestore          = "restore"           // back from a credential prompt, game or launcher
+	ReasonRequest          = "request"           // switched on request
+)
+
+// Change describes an input method switch made by the switcher
+type Change struct {
+	InputMethod string
+	Class       string
+	Title       string
+	Reason      string
+}
@@ -0,0 +1,116 @@
+package inputmethod
+
+import (
+	"strings"
+	"sync"
+)
+
+// clientTable maps window addresses to clients. It is built once from the
+// compositor's client list and then kept up to date from events, so focus
+// changes can be resolved without a round-trip.
+type clientTable struct {
+	mutex   sync.RWMutex
+	clients map[string]*ClientInfo
+}
+
+func newClientTable() *clientTable {
+	return &clientTable{
+		clients: make(map[string]*ClientInfo),
+	}
+}
+
+// Reset replaces the table contents
+func (t *clientTable) Reset(clients []ClientInfo) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.clients = make(map[string]*ClientInfo, len(clients))
+	for i := range clients {
+		client := clients[i]
+		t.clients[client.Address] = &client
+	}
+}
+
+// Get returns a copy of the client with the given address
+func (t *clientTable) Get(address string) (*ClientInfo, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	client, exists := t.clients[address]
+	if !exists {
+		return nil, false
+	}
+
+	clientCopy := *client
+	return &clientCopy, true
+}
+
+// Put adds or replaces a client
+func (t *clientTable) Put(client *ClientInfo) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	clientCopy := *client
+	t.clients[client.Address] = &clientCopy
+}
+
+// Update modifies the client with the given address in place, reporting
+// whether it exists
+func (t *clientTable) Update(address string, update func(client *ClientInfo)) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	client, exists := t.clients[address]
+	if exists {
+		update(client)
+	}
+	return exists
+}
+
+// Complete replaces a partial client with its full properties, unless the
+// window was closed or completed in the meantime
+func (t *clientTable) Complete(client *ClientInfo) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	existing, exists := t.clients[client.Address]
+	if !exists || !existing.partial {
+		return false
+	}
+
+	clientCopy := *client
+	clientCopy.partial = false
+	t.clients[client.Address] = &clientCopy
+	return true
+}
+
+// Remove deletes the client with the given address
+func (t *clientTable) Remove(address string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.clients, address)
+}
+
+// HasWorkspace checks if any client is on the named workspace
+func (t *clientTable) HasWorkspace(name string) bool {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	for _, client := range t.clients {
+		if client.Workspace.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAddress converts an event address ("55d0c0a0") to the form used
+// in JSON replies ("0x55d0c0a0")
+func normalizeAddress(address string) string {
+	address = strings.TrimSpace(address)
+	if address == "" || strings.HasPrefix(address, "0x") {
+		return address
+	}
+	return "0x" + address
+}
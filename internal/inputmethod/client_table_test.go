@@ -0,0 +1,235 @@
+package inputmethod
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHyprland answers requests on a Hyprland request socket with fixed
+// replies and records the commands it received
+type fakeHyprland struct {
+	mutex    sync.Mutex
+	replies  map[string]string
+	commands []string
+}
+
+// startFakeHyprland serves a request socket in a temporary runtime directory
+func startFakeHyprland(t *testing.T, replies map[string]string) *fakeHyprland {
+	t.Helper()
+
+	runtimeDir := t.TempDir()
+	instanceDir := filepath.Join(runtimeDir, "hypr", "test")
+	if err := os.MkdirAll(instanceDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "test")
+
+	listener, err := net.Listen("unix", filepath.Join(instanceDir, ".socket.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	fake := &fakeHyprland{replies: replies}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			buffer := make([]byte, 4096)
+			n, _ := conn.Read(buffer)
+			command := string(buffer[:n])
+
+			fake.mutex.Lock()
+			fake.commands = append(fake.commands, command)
+			reply, exists := fake.replies[command]
+			fake.mutex.Unlock()
+
+			if !exists {
+				reply = "unknown request"
+			}
+			conn.Write([]byte(reply))
+			conn.Close()
+		}
+	}()
+
+	return fake
+}
+
+// requests returns how often a command was received
+func (f *fakeHyprland) requests(command string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	count := 0
+	for _, received := range f.commands {
+		if received == command {
+			count++
+		}
+	}
+	return count
+}
+
+// waitForClient polls the table until the check passes for the client
+func waitForClient(t *testing.T, table *clientTable, address string, check func(client *ClientInfo) bool) *ClientInfo {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if client, exists := table.Get(address); exists && check(client) {
+			return client
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("client %s never reached the expected state", address)
+	return nil
+}
+
+func TestClientTableWindowEvents(t *testing.T) {
+	startFakeHyprland(t, nil)
+	source := NewHyprlandSource()
+	source.activeWorkspace = WorkspaceInfo{ID: 2, Name: "2"}
+
+	if event := source.updateClients("openwindow", "55d0c0a0,2,kitty,zsh"); event != nil {
+		t.Errorf("openwindow emitted %+v", event)
+	}
+
+	client, exists := source.clients.Get("0x55d0c0a0")
+	if !exists {
+		t.Fatal("openwindow did not add the window")
+	}
+	if !client.partial || client.Class != "kitty" || client.Title != "zsh" || client.InitialClass != "kitty" ||
+		client.Workspace != (WorkspaceInfo{ID: 2, Name: "2"}) {
+		t.Errorf("unexpected opened window: %+v", client)
+	}
+
+	// Title changes of unfocused windows only update the table
+	if event := source.updateClients("windowtitlev2", "55d0c0a0,vim"); event != nil {
+		t.Errorf("windowtitlev2 of an unfocused window emitted %+v", event)
+	}
+	if client, _ := source.clients.Get("0x55d0c0a0"); client.Title != "vim" {
+		t.Errorf("title = %q, want vim", client.Title)
+	}
+
+	source.focusedAddress = "0x55d0c0a0"
+	event := source.updateClients("windowtitlev2", "55d0c0a0,vim README.md")
+	if event == nil || event.Type != WindowTitleChanged || event.Client.Title != "vim README.md" {
+		t.Errorf("windowtitlev2 of the focused window emitted %+v", event)
+	}
+
+	source.updateClients("movewindowv2", "55d0c0a0,5,code")
+	if client, _ := source.clients.Get("0x55d0c0a0"); client.Workspace != (WorkspaceInfo{ID: 5, Name: "code"}) {
+		t.Errorf("workspace = %+v, want 5 code", client.Workspace)
+	}
+	if !source.clients.HasWorkspace("code") || source.clients.HasWorkspace("2") {
+		t.Error("HasWorkspace does not follow moved windows")
+	}
+
+	event = source.updateClients("closewindow", "55d0c0a0")
+	if event == nil || event.Type != WindowClosed || event.Client.Address != "0x55d0c0a0" {
+		t.Errorf("closewindow emitted %+v", event)
+	}
+	if _, exists := source.clients.Get("0x55d0c0a0"); exists {
+		t.Error("closewindow did not remove the window")
+	}
+
+	// Events for unknown windows are ignored
+	if source.clients.Update("0xdead", func(client *ClientInfo) {}) {
+		t.Error("Update reported an unknown window")
+	}
+}
+
+func TestClientTableComplete(t *testing.T) {
+	table := newClientTable()
+	table.Reset([]ClientInfo{{Address: "0x1", Class: "full"}})
+	table.Put(&ClientInfo{Address: "0x2", Class: "kitty", partial: true})
+
+	if table.Complete(&ClientInfo{Address: "0x1", Class: "stale"}) {
+		t.Error("Complete replaced a full client")
+	}
+	if table.Complete(&ClientInfo{Address: "0x3", Class: "closed"}) {
+		t.Error("Complete added a closed window")
+	}
+	if !table.Complete(&ClientInfo{Address: "0x2", Class: "kitty", PID: 42}) {
+		t.Error("Complete did not replace the partial client")
+	}
+
+	if client, _ := table.Get("0x2"); client.partial || client.PID != 42 {
+		t.Errorf("unexpected completed client: %+v", client)
+	}
+	if client, _ := table.Get("0x1"); client.Class != "full" {
+		t.Errorf("unexpected full client: %+v", client)
+	}
+}
+
+func TestActiveWindowV2KnownWindow(t *testing.T) {
+	fake := startFakeHyprland(t, nil)
+	source := NewHyprlandSource()
+	source.clients.Reset([]ClientInfo{{Address: "0x1", Class: "firefox", PID: 7}})
+
+	client, err := source.handleActiveWindowV2Event("1")
+	if err != nil || client == nil || client.Class != "firefox" {
+		t.Fatalf("got %+v, %v", client, err)
+	}
+
+	if fake.requests("j/activewindow") != 0 || fake.requests("j/clients") != 0 {
+		t.Error("a known window was resolved with a request")
+	}
+}
+
+func TestActiveWindowV2PartialWindow(t *testing.T) {
+	fake := startFakeHyprland(t, map[string]string{
+		// Focus already moved on, which must not drop the event
+		"j/activewindow": `{"address":"0x2","class":"foot"}`,
+		"j/clients":      `[{"address":"0x1","class":"kitty","title":"zsh","pid":42},{"address":"0x2","class":"foot"}]`,
+	})
+	source := NewHyprlandSource()
+	source.updateClients("openwindow", "1,1,kitty,zsh")
+
+	client, err := source.handleActiveWindowV2Event("1")
+	if err != nil || client == nil {
+		t.Fatalf("got %+v, %v", client, err)
+	}
+	if client.Address != "0x1" || client.Class != "kitty" {
+		t.Errorf("unexpected partial client: %+v", client)
+	}
+	if fake.requests("j/activewindow") != 0 {
+		t.Error("a partial window was resolved with an activewindow request")
+	}
+
+	completed := waitForClient(t, source.clients, "0x1", func(client *ClientInfo) bool { return !client.partial })
+	if completed.PID != 42 {
+		t.Errorf("completed client has PID %d, want 42", completed.PID)
+	}
+}
+
+func TestActiveWindowV2UnknownWindow(t *testing.T) {
+	startFakeHyprland(t, map[string]string{
+		"j/activewindow": `{"address":"0x2","class":"foot"}`,
+		"j/clients":      `[{"address":"0x1","class":"kitty","pid":42},{"address":"0x2","class":"foot"}]`,
+	})
+	source := NewHyprlandSource()
+
+	client, err := source.handleActiveWindowV2Event("1")
+	if err != nil || client == nil || client.Address != "0x1" {
+		t.Fatalf("address mismatch dropped the event: %+v, %v", client, err)
+	}
+
+	// The reply still describes the window focused next
+	if other, exists := source.clients.Get("0x2"); !exists || other.Class != "foot" {
+		t.Errorf("the focused window from the reply was not remembered: %+v", other)
+	}
+
+	completed := waitForClient(t, source.clients, "0x1", func(client *ClientInfo) bool { return !client.partial })
+	if completed.Class != "kitty" || completed.PID != 42 {
+		t.Errorf("unexpected completed client: %+v", completed)
+	}
+}
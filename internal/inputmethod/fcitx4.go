@@ -0,0 +1,215 @@
+package inputmethod
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"hypr-input-switcher/pkg/logger"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	fcitx4Service   = "org.fcitx.Fcitx"
+	fcitx4Path      = "/inputmethod"
+	fcitx4Interface = "org.fcitx.Fcitx.InputMethod"
+)
+
+// Fcitx4 talks to legacy fcitx (4.x) over its D-Bus InputMethod interface,
+// falling back to fcitx-remote
+type Fcitx4 struct{}
+
+func NewFcitx4() *Fcitx4 {
+	return &Fcitx4{}
+}
+
+// IsAvailable checks if fcitx4 is available
+func (f *Fcitx4) IsAvailable() bool {
+	if f.serviceName() != "" {
+		return true
+	}
+
+	// Check if fcitx-remote is available
+	if _, err := exec.LookPath("fcitx-remote"); err != nil {
+		return false
+	}
+
+	// fcitx-remote prints the state, or fails when fcitx is not running
+	cmd := exec.Command("fcitx-remote")
+	_, err := cmd.Output()
+	return err == nil
+}
+
+// IsRunning checks if fcitx4 owns its name on the session bus while fcitx5
+// does not, since fcitx5 can provide the fcitx4 interface for compatibility
+func (f *Fcitx4) IsRunning() bool {
+	if hasOwner, err := sessionBusHasOwner("org.fcitx.Fcitx5"); err != nil || hasOwner {
+		return false
+	}
+
+	return f.serviceName() != ""
+}
+
+// serviceName returns the bus name fcitx4 registered, which carries the X
+// display number (org.fcitx.Fcitx-0), or an empty string if none is found
+func (f *Fcitx4) serviceName() string {
+	var candidates []string
+
+	display := strings.TrimPrefix(os.Getenv("DISPLAY"), ":")
+	if idx := strings.Index(display, "."); idx > 0 {
+		display = display[:idx]
+	}
+	if display != "" {
+		candidates = append(candidates, fmt.Sprintf("%s-%s", fcitx4Service, display))
+	}
+	candidates = append(candidates, fcitx4Service+"-0", fcitx4Service)
+
+	for _, name := range candidates {
+		if hasOwner, err := sessionBusHasOwner(name); err == nil && hasOwner {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// GetCurrentIM gets the name of the current fcitx4 input method
+func (f *Fcitx4) GetCurrentIM() string {
+	// Try D-Bus first
+	if currentIM := f.getCurrentViaDBus(); currentIM != "unknown" {
+		return currentIM
+	}
+
+	// Fallback to fcitx-remote
+	output, err := exec.Command("fcitx-remote", "-n").Output()
+	if err != nil {
+		return "unknown"
+	}
+
+	currentIM := strings.TrimSpace(string(output))
+	if currentIM == "" {
+		return "unknown"
+	}
+
+	return currentIM
+}
+
+// getCurrentViaDBus gets current input method via D-Bus
+func (f *Fcitx4) getCurrentViaDBus() string {
+	service := f.serviceName()
+	if service == "" {
+		return "unknown"
+	}
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		logger.Debugf("Failed to connect to session bus: %v", err)
+		return "unknown"
+	}
+
+	obj := conn.Object(service, fcitx4Path)
+	var currentIM string
+
+	err = obj.Call(fcitx4Interface+".GetCurrentIM", 0).Store(&currentIM)
+	if err != nil {
+		logger.Debugf("Failed to get current fcitx4 input method via D-Bus: %v", err)
+		return "unknown"
+	}
+
+	logger.Debugf("Current fcitx4 input method via D-Bus: %s", currentIM)
+	return currentIM
+}
+
+// SetCurrentIM switches to the named fcitx4 input method
+func (f *Fcitx4) SetCurrentIM(name string) error {
+	// Try D-Bus first
+	if err := f.call("SetCurrentIM", name); err == nil {
+		logger.Debugf("Successfully switched fcitx4 to %s via D-Bus", name)
+		return nil
+	}
+
+	// Fallback to fcitx-remote
+	logger.Debugf("Using fcitx-remote fallback to switch to %s", name)
+	return exec.Command("fcitx-remote", "-s", name).Run()
+}
+
+// Inactivate switches fcitx4 to direct keyboard input
+func (f *Fcitx4) Inactivate() error {
+	// Try D-Bus first
+	if err := f.call("InactivateIM"); err == nil {
+		logger.Debug("Successfully inactivated fcitx4 via D-Bus")
+		return nil
+	}
+
+	// Fallback to fcitx-remote
+	logger.Debug("Using fcitx-remote fallback to inactivate")
+	return exec.Command("fcitx-remote", "-c").Run()
+}
+
+// GetInputMethods returns the names of the enabled fcitx4 input methods
+func (f *Fcitx4) GetInputMethods() ([]string, error) {
+	service := f.serviceName()
+	if service == "" {
+		return nil, fmt.Errorf("fcitx4 is not running")
+	}
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	// IMList is a(sssb): display name, unique name, language code, enabled
+	var imList []struct {
+		DisplayName string
+		UniqueName  string
+		LangCode    string
+		Enabled     bool
+	}
+
+	variant, err := conn.Object(service, fcitx4Path).GetProperty(fcitx4Interface + ".IMList")
+	if err != nil {
+		return nil, err
+	}
+	if err := variant.Store(&imList); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, im := range imList {
+		if im.Enabled {
+			names = append(names, im.UniqueName)
+		}
+	}
+
+	return names, nil
+}
+
+// call invokes a method on the fcitx4 InputMethod interface
+func (f *Fcitx4) call(method string, args ...interface{}) error {
+	service := f.serviceName()
+	if service == "" {
+		return fmt.Errorf("fcitx4 is not running")
+	}
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return err
+	}
+
+	return conn.Object(service, fcitx4Path).Call(fcitx4Interface+"."+method, 0, args...).Err
+}
+
+// sessionBusHasOwner checks if a name is owned on the session bus. Like the
+// other helpers it uses the shared connection, which must never be closed.
+func sessionBusHasOwner(name string) (bool, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return false, err
+	}
+
+	var hasOwner bool
+	err = conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, name).Store(&hasOwner)
+	return hasOwner, err
+}
@@ -0,0 +1,97 @@
+package inputmethod
+
+import (
+	"fmt"
+	"sort"
+
+	"hypr-input-switcher/internal/config"
+	"hypr-input-switcher/pkg/logger"
+)
+
+// Fcitx4Backend drives legacy fcitx, mapping logical input methods to
+// fcitx4 input method names via input_methods
+type Fcitx4Backend struct {
+	fcitx4       *Fcitx4
+	inputMethods map[string]string // inputMethod -> fcitx4 input method name
+}
+
+// newFcitx4Backend creates the fcitx4 backend
+func newFcitx4Backend(cfg *config.Config) Backend {
+	return &Fcitx4Backend{
+		fcitx4:       NewFcitx4(),
+		inputMethods: cfg.InputMethods,
+	}
+}
+
+// Name returns the backend name
+func (b *Fcitx4Backend) Name() string {
+	return "fcitx4"
+}
+
+// Detect checks if fcitx4 is running and fcitx5 is not
+func (b *Fcitx4Backend) Detect() bool {
+	return b.fcitx4.IsRunning()
+}
+
+// IsAvailable checks if fcitx4 is available
+func (b *Fcitx4Backend) IsAvailable() bool {
+	return b.fcitx4.IsAvailable()
+}
+
+// GetCurrent returns the logical name of the current fcitx4 input method.
+// Unmapped input methods are reported by their fcitx4 name.
+func (b *Fcitx4Backend) GetCurrent() string {
+	currentIM := b.fcitx4.GetCurrentIM()
+	if currentIM == "unknown" {
+		return "unknown"
+	}
+
+	if inputMethod, mapped := lookupInputMethod(b.inputMethods, currentIM); mapped {
+		return inputMethod
+	}
+
+	logger.Debugf("Fcitx4 input method %s is not mapped in input_methods", currentIM)
+	return currentIM
+}
+
+// Switch switches to the fcitx4 input method mapped to the given logical name
+func (b *Fcitx4Backend) Switch(inputMethod string) error {
+	name, mapped := b.inputMethods[inputMethod]
+	if !mapped || name == "" {
+		if inputMethod == "english" {
+			return b.fcitx4.Inactivate()
+		}
+		return fmt.Errorf("no fcitx4 input method configured for: %s", inputMethod)
+	}
+
+	return b.fcitx4.SetCurrentIM(name)
+}
+
+// Deactivate deactivates fcitx4, leaving direct keyboard input
+func (b *Fcitx4Backend) Deactivate() error {
+	return b.fcitx4.Inactivate()
+}
+
+// ListInputMethods returns the configured input methods whose fcitx4 input
+// method is enabled, or all configured ones if the list cannot be read
+func (b *Fcitx4Backend) ListInputMethods() []string {
+	enabled, err := b.fcitx4.GetInputMethods()
+	if err != nil {
+		logger.Debugf("Failed to list fcitx4 input methods: %v", err)
+		return sortedKeys(b.inputMethods)
+	}
+
+	available := make(map[string]bool)
+	for _, name := range enabled {
+		available[name] = true
+	}
+
+	var methods []string
+	for inputMethod, name := range b.inputMethods {
+		if available[name] {
+			methods = append(methods, inputMethod)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
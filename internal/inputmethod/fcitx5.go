@@ -33,8 +33,20 @@ func (f *Fcitx5) IsAvailable() bool {
 	return err == nil
 }
 
-// GetCurrent gets current input method via fcitx5
-func (f *Fcitx5) GetCurrent() string {
+// IsRunning checks if fcitx5 owns its name on the session bus
+func (f *Fcitx5) IsRunning() bool {
+	hasOwner, err := sessionBusHasOwner("org.fcitx.Fcitx5")
+	if err != nil {
+		logger.Debugf("Failed to query fcitx5 name owner: %v", err)
+		// Without a session bus, fall back to fcitx5-remote
+		return f.IsAvailable()
+	}
+
+	return hasOwner
+}
+
+// GetCurrentIM gets the name of the current fcitx5 input method
+func (f *Fcitx5) GetCurrentIM() string {
 	// Try D-Bus first
 	if currentIM := f.getCurrentViaDBus(); currentIM != "unknown" {
 		return currentIM
@@ -48,13 +60,11 @@ func (f *Fcitx5) GetCurrent() string {
 	}
 
 	currentIM := strings.TrimSpace(string(output))
-
-	// If it's rime, return the specific identifier
-	if currentIM == f.rimeInputMethod {
-		return "rime"
+	if currentIM == "" {
+		return "unknown"
 	}
 
-	return "english"
+	return currentIM
 }
 
 // getCurrentViaDBus gets current input method via D-Bus
@@ -64,7 +74,6 @@ func (f *Fcitx5) getCurrentViaDBus() string {
 		logger.Debugf("Failed to connect to session bus: %v", err)
 		return "unknown"
 	}
-	defer conn.Close()
 
 	obj := conn.Object("org.fcitx.Fcitx5", "/controller")
 	var currentIM string
@@ -76,17 +85,12 @@ func (f *Fcitx5) getCurrentViaDBus() string {
 	}
 
 	logger.Debugf("Current input method via D-Bus: %s", currentIM)
+	return currentIM
+}
 
-	// If it's rime, return rime identifier
-	if currentIM == f.rimeInputMethod {
-		return "rime"
-	}
-
-	if strings.Contains(currentIM, "keyboard") {
-		return "english"
-	}
-
-	return "english"
+// IsRime checks if the given fcitx5 input method name is the Rime input method
+func (f *Fcitx5) IsRime(name string) bool {
+	return name == f.rimeInputMethod
 }
 
 // SwitchToEnglish switches to English input method
@@ -110,7 +114,6 @@ func (f *Fcitx5) switchToEnglishViaDBus() error {
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
 
 	obj := conn.Object("org.fcitx.Fcitx5", "/controller")
 	call := obj.Call("org.fcitx.Fcitx.Controller1.Deactivate", 0)
@@ -141,7 +144,6 @@ func (f *Fcitx5) switchToRimeViaDBus() error {
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
 
 	// Step 1: Activate input method
 	obj := conn.Object("org.fcitx.Fcitx5", "/controller")
@@ -176,3 +178,35 @@ func (f *Fcitx5) switchToRimeFallback() error {
 	cmd = exec.Command("fcitx5-remote", "-s", f.rimeInputMethod)
 	return cmd.Run()
 }
+
+// SetCurrentIM switches to the named fcitx5 input method
+func (f *Fcitx5) SetCurrentIM(name string) error {
+	// Try D-Bus first
+	if err := f.setCurrentIMViaDBus(name); err == nil {
+		return nil
+	}
+
+	// Fallback to fcitx5-remote
+	logger.Debugf("Using fcitx5-remote fallback to switch to %s", name)
+	cmd := exec.Command("fcitx5-remote", "-s", name)
+	return cmd.Run()
+}
+
+// setCurrentIMViaDBus switches input method via D-Bus
+func (f *Fcitx5) setCurrentIMViaDBus(name string) error {
+	logger.Debugf("Switching to input method %s via D-Bus", name)
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return err
+	}
+
+	obj := conn.Object("org.fcitx.Fcitx5", "/controller")
+	call := obj.Call("org.fcitx.Fcitx.Controller1.SetCurrentIM", 0, name)
+	if call.Err != nil {
+		return call.Err
+	}
+
+	logger.Debugf("Successfully switched to %s via D-Bus", name)
+	return nil
+}
@@ -0,0 +1,152 @@
+package inputmethod
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"hypr-input-switcher/internal/config"
+	"hypr-input-switcher/pkg/logger"
+)
+
+// Fcitx5Backend drives Fcitx5. Logical input methods are mapped to fcitx5
+// input method names via input_methods; the Rime input method additionally
+// selects a schema from rime_schemas.
+type Fcitx5Backend struct {
+	fcitx5        *Fcitx5
+	rime          *Rime
+	inputMethods  map[string]string // inputMethod -> fcitx5 input method name
+	switchEnglish bool              // english uses its input_methods entry instead of deactivating
+}
+
+// newFcitx5Backend creates the Fcitx5 backend, or nil if fcitx5 is disabled
+func newFcitx5Backend(cfg *config.Config) Backend {
+	if !cfg.Fcitx5.Enabled {
+		return nil
+	}
+
+	return &Fcitx5Backend{
+		fcitx5:        NewFcitx5(cfg.Fcitx5.RimeInputMethod),
+		rime:          NewRime(cfg.RimeSchemas),
+		inputMethods:  cfg.InputMethods,
+		switchEnglish: cfg.Fcitx5.SwitchEnglish,
+	}
+}
+
+// Name returns the backend name
+func (b *Fcitx5Backend) Name() string {
+	return "fcitx5"
+}
+
+// Detect checks if fcitx5 is running
+func (b *Fcitx5Backend) Detect() bool {
+	return b.fcitx5.IsRunning()
+}
+
+// IsAvailable checks if fcitx5 is available
+func (b *Fcitx5Backend) IsAvailable() bool {
+	return b.fcitx5.IsAvailable()
+}
+
+// GetCurrent returns the logical name of the current fcitx5 input method.
+// Rime is resolved by its active schema; other engines by reverse lookup in
+// input_methods. Unmapped engines are reported by their fcitx5 name.
+func (b *Fcitx5Backend) GetCurrent() string {
+	currentIM := b.fcitx5.GetCurrentIM()
+	if currentIM == "unknown" {
+		return "unknown"
+	}
+
+	inputMethod, mapped := lookupInputMethod(b.inputMethods, currentIM)
+
+	// If it's Rime, get the specific input method based on schema
+	if b.fcitx5.IsRime(currentIM) {
+		fallback := currentIM
+		if mapped {
+			fallback = inputMethod
+		}
+		return b.rime.GetCurrentInputMethod(fallback)
+	}
+
+	if mapped {
+		return inputMethod
+	}
+
+	// Keep treating keyboard layouts as English when it is not mapped
+	if _, exists := b.inputMethods["english"]; !exists && strings.HasPrefix(currentIM, "keyboard-") {
+		return "english"
+	}
+
+	logger.Debugf("Fcitx5 input method %s is not mapped in input_methods", currentIM)
+	return currentIM
+}
+
+// Switch switches to the fcitx5 input method mapped to the given logical
+// name. English deactivates fcitx5 unless switch_english is set.
+func (b *Fcitx5Backend) Switch(inputMethod string) error {
+	// English deactivates fcitx5 as it always has, unless switch_english is set
+	if inputMethod == "english" && !b.switchEnglish {
+		return b.fcitx5.SwitchToEnglish()
+	}
+
+	name, mapped := b.inputMethods[inputMethod]
+	_, hasSchema := b.rime.schemas[inputMethod]
+
+	if b.fcitx5.IsRime(name) || (!mapped && hasSchema) {
+		return b.switchToRime(inputMethod, hasSchema)
+	}
+
+	if !mapped || name == "" {
+		if inputMethod == "english" {
+			return b.fcitx5.SwitchToEnglish()
+		}
+		return fmt.Errorf("no fcitx5 input method configured for: %s", inputMethod)
+	}
+
+	return b.fcitx5.SetCurrentIM(name)
+}
+
+// Deactivate deactivates fcitx5, leaving direct keyboard input
+func (b *Fcitx5Backend) Deactivate() error {
+	return b.fcitx5.SwitchToEnglish()
+}
+
+// switchToRime switches to Rime and selects the schema for the input method
+func (b *Fcitx5Backend) switchToRime(inputMethod string, hasSchema bool) error {
+	if err := b.fcitx5.SwitchToRime(); err != nil {
+		return fmt.Errorf("failed to switch to Rime: %w", err)
+	}
+
+	if !hasSchema {
+		return nil
+	}
+
+	// Wait a bit for the switch to take effect
+	time.Sleep(100 * time.Millisecond)
+
+	return b.rime.SwitchSchema(inputMethod)
+}
+
+// ListInputMethods returns every configured input method and Rime schema
+func (b *Fcitx5Backend) ListInputMethods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, source := range []map[string]string{b.inputMethods, b.rime.schemas} {
+		for inputMethod := range source {
+			if !seen[inputMethod] {
+				seen[inputMethod] = true
+				methods = append(methods, inputMethod)
+			}
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// Status reports if the Rime addon is reachable
+func (b *Fcitx5Backend) Status() map[string]interface{} {
+	return map[string]interface{}{
+		"rime_available": b.rime.IsAvailable(),
+	}
+}
@@ -0,0 +1,686 @@
+package inputmethod
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"hypr-input-switcher/internal/hyprland"
+	"hypr-input-switcher/pkg/logger"
+)
+
+// HyprlandSource tracks window focus through Hyprland's socket2 event stream
+type HyprlandSource struct {
+	client         *hyprland.Client
+	clients        *clientTable
+	focusedAddress string // address of the last emitted focused window
+	seenV2         bool   // activewindowv2 supersedes activewindow once seen
+	seenTitleV2    bool   // windowtitlev2 supersedes windowtitle once seen
+
+	monitors        map[int]string // monitor names by ID
+	activeMonitor   string         // focused monitor name
+	activeWorkspace WorkspaceInfo  // active workspace on the focused monitor
+}
+
+// hyprlandMonitor is the subset of a monitor from hyprctl monitors -j
+type hyprlandMonitor struct {
+	ID               int           `json:"id"`
+	Name             string        `json:"name"`
+	Focused          bool          `json:"focused"`
+	ActiveWorkspace  WorkspaceInfo `json:"activeWorkspace"`
+	SpecialWorkspace WorkspaceInfo `json:"specialWorkspace"`
+}
+
+func NewHyprlandSource() *HyprlandSource {
+	return &HyprlandSource{
+		client:  hyprland.NewClient(),
+		clients: newClientTable(),
+	}
+}
+
+// Name returns the compositor name
+func (h *HyprlandSource) Name() string {
+	return "hyprland"
+}
+
+// IsAvailable checks if Hyprland answers requests
+func (h *HyprlandSource) IsAvailable() bool {
+	if !h.client.IsAvailable() {
+		logger.Error("Hyprland is not reachable via its request socket or hyprctl")
+		return false
+	}
+	return true
+}
+
+// ActiveWindow returns the currently focused window
+func (h *HyprlandSource) ActiveWindow() (*ClientInfo, error) {
+	if err := h.loadMonitors(); err != nil {
+		logger.Debugf("Failed to load Hyprland monitors: %v", err)
+	}
+
+	clientInfo, err := h.getCurrentClient()
+	if err != nil {
+		return nil, err
+	}
+
+	h.focusedAddress = clientInfo.Address
+	if clientInfo.Address != "" {
+		h.clients.Put(clientInfo)
+	} else {
+		// Nothing focused, describe the empty workspace instead
+		clientInfo.Workspace = h.activeWorkspace
+	}
+	h.resolveMonitor(clientInfo)
+	return clientInfo, nil
+}
+
+// loadMonitors refreshes the monitor names and the focused monitor and workspace
+func (h *HyprlandSource) loadMonitors() error {
+	var monitors []hyprlandMonitor
+	if err := h.client.Monitors(&monitors); err != nil {
+		return err
+	}
+
+	h.monitors = make(map[int]string, len(monitors))
+	for _, monitor := range monitors {
+		h.monitors[monitor.ID] = monitor.Name
+		if monitor.Focused {
+			h.activeMonitor = monitor.Name
+			h.activeWorkspace = monitor.ActiveWorkspace
+			if monitor.SpecialWorkspace.Name != "" {
+				h.activeWorkspace = monitor.SpecialWorkspace
+			}
+		}
+	}
+
+	logger.Debugf("Loaded %d Hyprland monitors", len(monitors))
+	return nil
+}
+
+// resolveMonitor fills in the monitor name of the focused window, which is
+// always on the focused monitor
+func (h *HyprlandSource) resolveMonitor(clientInfo *ClientInfo) {
+	if h.activeMonitor != "" {
+		clientInfo.Monitor = h.activeMonitor
+		return
+	}
+	clientInfo.Monitor = h.monitors[clientInfo.MonitorID]
+}
+
+// loadClients rebuilds the client table from the full client list
+func (h *HyprlandSource) loadClients() error {
+	var clients []ClientInfo
+	if err := h.client.Clients(&clients); err != nil {
+		return err
+	}
+
+	h.clients.Reset(clients)
+	logger.Debugf("Loaded %d Hyprland clients", len(clients))
+	return nil
+}
+
+// Run monitors Hyprland events until the context is cancelled
+func (h *HyprlandSource) Run(ctx context.Context, events chan<- WindowEvent) error {
+	// Get Hyprland IPC socket path
+	socketPath := h.getHyprlandEventSocket()
+	if socketPath == "" {
+		return fmt.Errorf("failed to get Hyprland event socket path")
+	}
+
+	logger.Debugf("Connecting to Hyprland event socket: %s", socketPath)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping input method switcher...")
+			return nil
+		default:
+		}
+
+		// Connect to socket
+		conn, err := net.Dial("unix", socketPath)
+		if err != nil {
+			logger.Errorf("Failed to connect to Hyprland event socket: %v", err)
+			// Retry after delay
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(5 * time.Second):
+				continue
+			}
+		}
+
+		logger.Debug("Connected to Hyprland event socket")
+
+		// Events may have been missed while disconnected
+		if err := h.loadClients(); err != nil {
+			logger.Warningf("Failed to load Hyprland clients: %v", err)
+		}
+		if err := h.loadMonitors(); err != nil {
+			logger.Warningf("Failed to load Hyprland monitors: %v", err)
+		}
+
+		// Monitor events
+		err = h.handleEvents(ctx, conn, events)
+		conn.Close()
+
+		if err != nil && err != context.Canceled {
+			logger.Errorf("Event monitoring error: %v", err)
+			// Retry after delay
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(5 * time.Second):
+				continue
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (h *HyprlandSource) handleEvents(ctx context.Context, conn net.Conn, events chan<- WindowEvent) error {
+	// Unblock the scanner when the context is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	scanner := bufio.NewScanner(conn)
+
+	for scanner.Scan() {
+		select {
+		case <-ctx.Done():
+			return context.Canceled
+		default:
+		}
+
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		// Parse event
+		parts := strings.SplitN(line, ">>", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		eventType := parts[0]
+		eventData := parts[1]
+
+		logger.Tracef("Received event: %s >> %s", eventType, eventData)
+
+		// Forward the raw event for backends tracking state from events
+		if err := sendEvent(ctx, events, WindowEvent{Type: CompositorEvent, Name: eventType, Data: eventData}); err != nil {
+			return err
+		}
+
+		// Handle window focus events - prefer activewindowv2 for better info
+		var clientInfo *ClientInfo
+		var err error
+		switch eventType {
+		case "activewindowv2":
+			if clientInfo, err = h.handleActiveWindowV2Event(eventData); err != nil {
+				logger.Warningf("Error handling activewindowv2 event: %v", err)
+			}
+		case "activewindow":
+			// Fallback for older Hyprland versions
+			if h.seenV2 {
+				break
+			}
+			if clientInfo, err = h.handleActiveWindowEvent(eventData); err != nil {
+				logger.Warningf("Error handling activewindow event: %v", err)
+			}
+		case "windowtitle":
+			// Fallback for older Hyprland versions
+			if h.seenTitleV2 || normalizeAddress(eventData) != h.focusedAddress {
+				break
+			}
+			if clientInfo, err := h.getCurrentClient(); err == nil && clientInfo.Address == h.focusedAddress {
+				h.clients.Put(clientInfo)
+				h.resolveMonitor(clientInfo)
+				if err := sendEvent(ctx, events, WindowEvent{Type: WindowTitleChanged, Client: clientInfo}); err != nil {
+					return err
+				}
+			}
+		case "openlayer", "closelayer":
+			// eventData format: "namespace"
+			layerEvent := WindowEvent{Type: LayerOpened, Name: eventData}
+			if eventType == "closelayer" {
+				layerEvent.Type = LayerClosed
+			}
+			if err := sendEvent(ctx, events, layerEvent); err != nil {
+				return err
+			}
+		case "workspacev2", "focusedmon", "focusedmonv2", "activespecial", "monitoraddedv2", "monitorremoved":
+			if event := h.updateWorkspace(eventType, eventData); event != nil {
+				if err := sendEvent(ctx, events, *event); err != nil {
+					return err
+				}
+			}
+		default:
+			if event := h.updateClients(eventType, eventData); event != nil {
+				if err := sendEvent(ctx, events, *event); err != nil {
+					return err
+				}
+			}
+		}
+
+		if clientInfo != nil {
+			h.focusedAddress = clientInfo.Address
+			h.resolveMonitor(clientInfo)
+			if err := sendEvent(ctx, events, WindowEvent{Type: WindowFocused, Client: clientInfo}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return context.Canceled
+	}
+
+	return scanner.Err()
+}
+
+func (h *HyprlandSource) handleActiveWindowV2Event(eventData string) (*ClientInfo, error) {
+	h.seenV2 = true
+
+	// eventData format: "windowaddress" (hex address without 0x prefix)
+	windowAddress := normalizeAddress(eventData)
+	if windowAddress == "" {
+		logger.Tracef("Empty activewindowv2 event data")
+		return nil, nil
+	}
+
+	logger.Tracef("Active window changed to address: %s", windowAddress)
+
+	// Check if this is the same window we're already tracking
+	if windowAddress == h.focusedAddress {
+		logger.Tracef("Same window address, skipping: %s", windowAddress)
+		return nil, nil
+	}
+
+	// Resolve the window locally, completing windows known only from their
+	// openwindow event in the background
+	if clientInfo, exists := h.clients.Get(windowAddress); exists {
+		if clientInfo.partial {
+			go h.completeClient(windowAddress)
+		}
+		return clientInfo, nil
+	}
+
+	// Unknown window, ask Hyprland and remember it
+	logger.Tracef("Window %s not in client table, querying Hyprland", windowAddress)
+	clientInfo, err := h.getCurrentClient()
+	if err == nil && clientInfo.Address == windowAddress {
+		h.clients.Put(clientInfo)
+		return clientInfo, nil
+	}
+
+	if err != nil {
+		logger.Debugf("Failed to get current client: %v", err)
+	} else {
+		// Focus moved on again, the reply still describes a real window
+		logger.Tracef("Event address mismatch: got %s, expected %s", clientInfo.Address, windowAddress)
+		if clientInfo.Address != "" {
+			h.clients.Put(clientInfo)
+		}
+	}
+
+	// Report the focus change with what is known and complete it later
+	clientInfo = &ClientInfo{Address: windowAddress, partial: true}
+	h.clients.Put(clientInfo)
+	go h.completeClient(windowAddress)
+	return clientInfo, nil
+}
+
+// completeClient fills in the properties of a partial client from the full
+// client list
+func (h *HyprlandSource) completeClient(address string) {
+	var clients []ClientInfo
+	if err := h.client.Clients(&clients); err != nil {
+		logger.Debugf("Failed to load Hyprland clients: %v", err)
+		return
+	}
+
+	for i := range clients {
+		if clients[i].Address == address {
+			if h.clients.Complete(&clients[i]) {
+				logger.Tracef("Completed window %s from the client list", address)
+			}
+			return
+		}
+	}
+}
+
+// updateClients keeps the client table in sync with window events. It
+// returns the event to emit for the change, if any.
+func (h *HyprlandSource) updateClients(eventType, eventData string) *WindowEvent {
+	switch eventType {
+	case "openwindow":
+		// eventData format: "address,workspacename,class,title"
+		parts := strings.SplitN(eventData, ",", 4)
+		if len(parts) < 4 {
+			return nil
+		}
+		workspace := WorkspaceInfo{Name: parts[1]}
+		if workspace.Name == h.activeWorkspace.Name {
+			// The event carries no workspace ID, but windows usually open on the active workspace
+			workspace.ID = h.activeWorkspace.ID
+		}
+		// The full properties are fetched once the window is first focused
+		h.clients.Put(&ClientInfo{
+			Address:      normalizeAddress(parts[0]),
+			Class:        parts[2],
+			Title:        parts[3],
+			Workspace:    workspace,
+			InitialClass: parts[2],
+			InitialTitle: parts[3],
+			partial:      true,
+		})
+
+	case "closewindow":
+		// eventData format: "address"
+		address := normalizeAddress(eventData)
+		h.clients.Remove(address)
+		return &WindowEvent{Type: WindowClosed, Client: &ClientInfo{Address: address}}
+
+	case "windowtitlev2":
+		h.seenTitleV2 = true
+
+		// eventData format: "address,title"
+		address, title, found := strings.Cut(eventData, ",")
+		if !found {
+			return nil
+		}
+		address = normalizeAddress(address)
+		h.clients.Update(address, func(client *ClientInfo) {
+			client.Title = title
+		})
+
+		if address == h.focusedAddress {
+			if clientInfo, exists := h.clients.Get(address); exists {
+				h.resolveMonitor(clientInfo)
+				return &WindowEvent{Type: WindowTitleChanged, Client: clientInfo}
+			}
+		}
+
+	case "changefloatingmode":
+		// eventData format: "address,floating"
+		address, floating, found := strings.Cut(eventData, ",")
+		if !found {
+			return nil
+		}
+		h.clients.Update(normalizeAddress(address), func(client *ClientInfo) {
+			client.Floating = floating == "1"
+		})
+
+	case "pin":
+		// eventData format: "address,pinned"
+		address, pinned, found := strings.Cut(eventData, ",")
+		if !found {
+			return nil
+		}
+		h.clients.Update(normalizeAddress(address), func(client *ClientInfo) {
+			client.Pinned = pinned == "1"
+		})
+
+	case "fullscreen":
+		// eventData format: "0" or "1", for the focused window. It doesn't
+		// tell maximizing from fullscreen, the activewindow JSON does.
+		clientInfo, err := h.getCurrentClient()
+		if err != nil || clientInfo.Address != h.focusedAddress {
+			h.clients.Update(h.focusedAddress, func(client *ClientInfo) {
+				if eventData == "0" {
+					client.Fullscreen = FullscreenNone
+				} else if client.Fullscreen == FullscreenNone {
+					client.Fullscreen = FullscreenFull
+				}
+			})
+			clientInfo, _ = h.clients.Get(h.focusedAddress)
+		} else {
+			h.clients.Put(clientInfo)
+		}
+
+		if clientInfo != nil {
+			h.resolveMonitor(clientInfo)
+			return &WindowEvent{Type: WindowStateChanged, Client: clientInfo}
+		}
+
+	case "movewindowv2":
+		// eventData format: "address,workspaceid,workspacename"
+		parts := strings.SplitN(eventData, ",", 3)
+		if len(parts) < 3 {
+			return nil
+		}
+		workspaceID, _ := strconv.Atoi(parts[1])
+		h.clients.Update(normalizeAddress(parts[0]), func(client *ClientInfo) {
+			client.Workspace = WorkspaceInfo{ID: workspaceID, Name: parts[2]}
+		})
+	}
+
+	return nil
+}
+
+// updateWorkspace tracks the focused monitor and workspace. It returns a
+// WorkspaceFocused event if focus moved onto an empty workspace.
+func (h *HyprlandSource) updateWorkspace(eventType, eventData string) *WindowEvent {
+	previous := h.activeWorkspace
+
+	switch eventType {
+	case "workspacev2":
+		// eventData format: "workspaceid,workspacename"
+		id, name, found := strings.Cut(eventData, ",")
+		if !found {
+			return nil
+		}
+		workspaceID, _ := strconv.Atoi(id)
+		h.activeWorkspace = WorkspaceInfo{ID: workspaceID, Name: name}
+
+	case "focusedmon":
+		// eventData format: "monitorname,workspacename"
+		monitor, name, found := strings.Cut(eventData, ",")
+		if !found {
+			return nil
+		}
+		h.activeMonitor = monitor
+		h.activeWorkspace.Name = name
+
+	case "focusedmonv2":
+		// eventData format: "monitorname,workspaceid"
+		monitor, id, found := strings.Cut(eventData, ",")
+		if !found {
+			return nil
+		}
+		h.activeMonitor = monitor
+		h.activeWorkspace.ID, _ = strconv.Atoi(id)
+
+	case "activespecial":
+		// eventData format: "workspacename,monitorname", with an empty
+		// name when the special workspace is closed
+		name, monitor, _ := strings.Cut(eventData, ",")
+		if name == "" || monitor != h.activeMonitor {
+			// Refresh the regular workspace the special one covered
+			if err := h.loadMonitors(); err != nil {
+				logger.Debugf("Failed to load Hyprland monitors: %v", err)
+			}
+			return nil
+		}
+		h.activeWorkspace = WorkspaceInfo{Name: name}
+
+	case "monitoraddedv2", "monitorremoved":
+		if err := h.loadMonitors(); err != nil {
+			logger.Debugf("Failed to load Hyprland monitors: %v", err)
+		}
+		return nil
+	}
+
+	// focusedmon and focusedmonv2 arrive in sequence, so the workspace is
+	// only compared once both halves are known
+	if h.activeWorkspace == previous || h.activeWorkspace.Name == "" || eventType == "focusedmon" {
+		return nil
+	}
+
+	if h.clients.HasWorkspace(h.activeWorkspace.Name) {
+		// A window on it gains focus and is reported through activewindowv2
+		return nil
+	}
+
+	logger.Tracef("Focused empty workspace: %s (monitor: %s)", h.activeWorkspace.Name, h.activeMonitor)
+	h.focusedAddress = ""
+	return &WindowEvent{
+		Type: WorkspaceFocused,
+		Client: &ClientInfo{
+			Workspace: h.activeWorkspace,
+			Monitor:   h.activeMonitor,
+		},
+	}
+}
+
+func (h *HyprlandSource) handleActiveWindowEvent(eventData string) (*ClientInfo, error) {
+	// eventData format: "class,title"
+	parts := strings.SplitN(eventData, ",", 2)
+	if len(parts) < 2 {
+		logger.Warningf("Invalid activewindow event data: %s", eventData)
+		return nil, nil
+	}
+
+	class := parts[0]
+	title := parts[1]
+
+	logger.Tracef("Active window changed: class=%s, title=%s", class, title)
+
+	// Get full client info for the active window
+	clientInfo, err := h.getCurrentClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current client: %w", err)
+	}
+
+	// Verify the event matches current window
+	if clientInfo.Class != class {
+		logger.Tracef("Event class mismatch: got %s, expected %s", class, clientInfo.Class)
+		return nil, nil
+	}
+
+	// Check if this is the same window we're already tracking
+	if clientInfo.Address == h.focusedAddress {
+		logger.Tracef("Same window address, skipping: %s", clientInfo.Address)
+		return nil, nil
+	}
+
+	return clientInfo, nil
+}
+
+func (h *HyprlandSource) getHyprlandEventSocket() string {
+	logger.Tracef("Searching for Hyprland IPC socket...")
+
+	// Get XDG_RUNTIME_DIR
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		logger.Tracef("XDG_RUNTIME_DIR not set, falling back to /tmp")
+		runtimeDir = "/tmp"
+	}
+	logger.Tracef("Using runtime directory: %s", runtimeDir)
+
+	// Try environment variables first
+	if hyprInstance := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE"); hyprInstance != "" {
+		logger.Tracef("Found HYPRLAND_INSTANCE_SIGNATURE: %s", hyprInstance)
+		socketPath := fmt.Sprintf("%s/hypr/%s/.socket2.sock", runtimeDir, hyprInstance)
+		logger.Tracef("Checking socket path: %s", socketPath)
+		if _, err := os.Stat(socketPath); err == nil {
+			logger.Debug("Found Hyprland IPC socket via environment")
+			return socketPath
+		} else {
+			logger.Tracef("Hyprland IPC Socket not found via environment: %v", err)
+		}
+	} else {
+		logger.Tracef("HYPRLAND_INSTANCE_SIGNATURE not set")
+	}
+
+	// Check if hypr directory exists in runtime dir
+	hyprDir := fmt.Sprintf("%s/hypr", runtimeDir)
+	if _, err := os.Stat(hyprDir); os.IsNotExist(err) {
+		logger.Errorf("Hyprland directory %s does not exist. Is Hyprland running?", hyprDir)
+		return ""
+	}
+
+	// List all directories in runtime/hypr/
+	entries, err := os.ReadDir(hyprDir)
+	if err != nil {
+		logger.Errorf("Failed to read Hyprland directory %s: %v", hyprDir, err)
+		return ""
+	}
+
+	logger.Tracef("Found %d entries in %s", len(entries), hyprDir)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			socketPath := fmt.Sprintf("%s/%s/.socket2.sock", hyprDir, entry.Name())
+			logger.Tracef("Checking socket: %s", socketPath)
+			if _, err := os.Stat(socketPath); err == nil {
+				logger.Debug("Found Hyprland event socket")
+				return socketPath
+			} else {
+				logger.Tracef("Socket not found: %v", err)
+			}
+		}
+	}
+
+	// Fallback: try to find socket using glob pattern in runtime dir
+	globPattern := fmt.Sprintf("%s/hypr/*/.socket2.sock", runtimeDir)
+	logger.Tracef("Trying glob pattern: %s", globPattern)
+	matches, err := filepath.Glob(globPattern)
+	if err != nil {
+		logger.Errorf("Glob pattern failed: %v", err)
+		return ""
+	}
+
+	logger.Tracef("Glob found %d matches", len(matches))
+	for _, match := range matches {
+		logger.Tracef("Glob match: %s", match)
+	}
+
+	if len(matches) == 0 {
+		logger.Error("No Hyprland event sockets found. Please check:")
+		logger.Error("1. Is Hyprland running?")
+		logger.Error("2. Are you running this inside a Hyprland session?")
+		logger.Errorf("3. Check if %s/hypr directory exists and contains instance directories", runtimeDir)
+
+		// List what's actually in runtime/hypr if it exists
+		if entries, err := os.ReadDir(hyprDir); err == nil {
+			logger.Errorf("Contents of %s:", hyprDir)
+			for _, entry := range entries {
+				logger.Errorf("  - %s (dir: %v)", entry.Name(), entry.IsDir())
+			}
+		}
+
+		// Also check for legacy /tmp/hypr path
+		logger.Trace("Checking legacy /tmp/hypr path...")
+		legacyPattern := "/tmp/hypr/*/.socket2.sock"
+		if legacyMatches, err := filepath.Glob(legacyPattern); err == nil && len(legacyMatches) > 0 {
+			logger.Debug("Found legacy socket")
+			return legacyMatches[0]
+		}
+
+		return ""
+	}
+
+	// Use the first available socket
+	logger.Debug("Using first available socket")
+	return matches[0]
+}
+
+func (h *HyprlandSource) getCurrentClient() (*ClientInfo, error) {
+	var clientInfo ClientInfo
+	if err := h.client.ActiveWindow(&clientInfo); err != nil {
+		return nil, err
+	}
+
+	return &clientInfo, nil
+}
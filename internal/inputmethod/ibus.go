@@ -0,0 +1,266 @@
+package inputmethod
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"hypr-input-switcher/pkg/logger"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	ibusService   = "org.freedesktop.IBus"
+	ibusPath      = "/org/freedesktop/IBus"
+	ibusInterface = "org.freedesktop.IBus"
+)
+
+type IBus struct {
+	address string // configured bus address, empty for auto-discovery
+}
+
+func NewIBus(address string) *IBus {
+	return &IBus{
+		address: address,
+	}
+}
+
+// IsAvailable checks if the IBus daemon can be reached
+func (i *IBus) IsAvailable() bool {
+	conn, err := i.connect()
+	if err != nil {
+		logger.Debugf("IBus is not available: %v", err)
+		return false
+	}
+	defer conn.Close()
+
+	call := conn.Object(ibusService, ibusPath).Call("org.freedesktop.DBus.Peer.Ping", 0)
+	return call.Err == nil
+}
+
+// GetAddress returns the IBus bus address, or an empty string if none was found
+func (i *IBus) GetAddress() string {
+	// Configured address always wins
+	if i.address != "" {
+		return i.address
+	}
+
+	// Try environment variable next
+	if address := os.Getenv("IBUS_ADDRESS"); address != "" {
+		return address
+	}
+
+	// Try the address file written by ibus-daemon
+	if address := i.readAddressFile(); address != "" {
+		return address
+	}
+
+	// Fallback to ibus CLI
+	output, err := exec.Command("ibus", "address").Output()
+	if err != nil {
+		return ""
+	}
+
+	address := strings.TrimSpace(string(output))
+	if address == "(null)" {
+		return ""
+	}
+	return address
+}
+
+// readAddressFile reads IBUS_ADDRESS from ~/.config/ibus/bus/<machine-id>-unix-<display>
+func (i *IBus) readAddressFile() string {
+	machineID := readMachineID()
+	if machineID == "" {
+		return ""
+	}
+
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		display = os.Getenv("WAYLAND_DISPLAY")
+	}
+	if display == "" {
+		display = "wayland-0"
+	}
+
+	// ":0.0" -> "0", "wayland-1" stays as is
+	display = strings.TrimPrefix(display, ":")
+	if idx := strings.Index(display, "."); idx > 0 {
+		display = display[:idx]
+	}
+
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configDir = filepath.Join(homeDir, ".config")
+	}
+
+	path := filepath.Join(configDir, "ibus", "bus", fmt.Sprintf("%s-unix-%s", machineID, display))
+	file, err := os.Open(path)
+	if err != nil {
+		logger.Tracef("IBus address file not found: %v", err)
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if address, found := strings.CutPrefix(scanner.Text(), "IBUS_ADDRESS="); found {
+			return address
+		}
+	}
+
+	return ""
+}
+
+// readMachineID returns the D-Bus machine id used to name the IBus address file
+func readMachineID() string {
+	for _, path := range []string{"/var/lib/dbus/machine-id", "/etc/machine-id"} {
+		if data, err := os.ReadFile(path); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return ""
+}
+
+// connect opens a private connection to the IBus bus
+func (i *IBus) connect() (*dbus.Conn, error) {
+	address := i.GetAddress()
+	if address == "" {
+		return nil, fmt.Errorf("IBus address not found")
+	}
+
+	conn, err := dbus.Dial(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// GetGlobalEngine gets the name of the current global engine
+func (i *IBus) GetGlobalEngine() string {
+	// Try D-Bus first
+	if engine := i.getGlobalEngineViaDBus(); engine != "unknown" {
+		return engine
+	}
+
+	// Fallback to ibus CLI
+	output, err := exec.Command("ibus", "engine").Output()
+	if err != nil {
+		return "unknown"
+	}
+
+	engine := strings.TrimSpace(string(output))
+	if engine == "" {
+		return "unknown"
+	}
+	return engine
+}
+
+// getGlobalEngineViaDBus gets the current global engine via D-Bus
+func (i *IBus) getGlobalEngineViaDBus() string {
+	conn, err := i.connect()
+	if err != nil {
+		logger.Debugf("Failed to connect to IBus: %v", err)
+		return "unknown"
+	}
+	defer conn.Close()
+
+	obj := conn.Object(ibusService, ibusPath)
+
+	// IBus 1.5 exposes the engine as a property, older versions as a method
+	desc, err := obj.GetProperty(ibusInterface + ".GlobalEngine")
+	if err != nil {
+		if err := obj.Call(ibusInterface+".GetGlobalEngine", 0).Store(&desc); err != nil {
+			logger.Debugf("Failed to get IBus global engine via D-Bus: %v", err)
+			return "unknown"
+		}
+	}
+
+	engine, err := engineDescName(desc)
+	if err != nil {
+		logger.Debugf("Failed to parse IBus engine description: %v", err)
+		return "unknown"
+	}
+
+	logger.Debugf("Current IBus engine via D-Bus: %s", engine)
+	return engine
+}
+
+// engineDescName extracts the engine name from a serialized IBusEngineDesc,
+// a struct of (type name, attachments, engine name, ...)
+func engineDescName(desc dbus.Variant) (string, error) {
+	// Unwrap nested variants
+	value := desc.Value()
+	for {
+		inner, ok := value.(dbus.Variant)
+		if !ok {
+			break
+		}
+		value = inner.Value()
+	}
+
+	fields, ok := value.([]interface{})
+	if !ok || len(fields) < 3 {
+		return "", fmt.Errorf("unexpected engine description: %s", desc.Signature())
+	}
+
+	name, ok := fields[2].(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected engine name type: %T", fields[2])
+	}
+
+	return name, nil
+}
+
+// SetGlobalEngine switches the global engine
+func (i *IBus) SetGlobalEngine(engine string) error {
+	// Try D-Bus first
+	if err := i.setGlobalEngineViaDBus(engine); err == nil {
+		return nil
+	} else {
+		logger.Debugf("Failed to set IBus engine via D-Bus: %v", err)
+	}
+
+	// Fallback to ibus CLI
+	logger.Debugf("Using ibus CLI fallback to switch to %s", engine)
+	return exec.Command("ibus", "engine", engine).Run()
+}
+
+// setGlobalEngineViaDBus switches the global engine via D-Bus
+func (i *IBus) setGlobalEngineViaDBus(engine string) error {
+	logger.Debugf("Switching IBus engine to %s via D-Bus", engine)
+
+	conn, err := i.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	obj := conn.Object(ibusService, ibusPath)
+	call := obj.Call(ibusInterface+".SetGlobalEngine", 0, engine)
+	if call.Err != nil {
+		return call.Err
+	}
+
+	logger.Debugf("Successfully switched IBus engine to %s via D-Bus", engine)
+	return nil
+}
@@ -0,0 +1,76 @@
+package inputmethod
+
+import (
+	"fmt"
+
+	"hypr-input-switcher/internal/config"
+	"hypr-input-switcher/pkg/logger"
+)
+
+// IBusBackend drives IBus, mapping logical input methods to IBus engine
+// names via input_methods (e.g. english: xkb:us::eng, japanese: mozc-jp)
+type IBusBackend struct {
+	ibus         *IBus
+	inputMethods map[string]string // inputMethod -> IBus engine name
+}
+
+// newIBusBackend creates the IBus backend
+func newIBusBackend(cfg *config.Config) Backend {
+	return &IBusBackend{
+		ibus:         NewIBus(cfg.IBus.Address),
+		inputMethods: cfg.InputMethods,
+	}
+}
+
+// Name returns the backend name
+func (b *IBusBackend) Name() string {
+	return "ibus"
+}
+
+// Detect checks if an IBus daemon is reachable
+func (b *IBusBackend) Detect() bool {
+	return b.ibus.IsAvailable()
+}
+
+// IsAvailable checks if IBus is available
+func (b *IBusBackend) IsAvailable() bool {
+	return b.ibus.IsAvailable()
+}
+
+// GetCurrent returns the logical name of the current global engine.
+// Unmapped engines are reported by their IBus name.
+func (b *IBusBackend) GetCurrent() string {
+	engine := b.ibus.GetGlobalEngine()
+	if engine == "unknown" {
+		return "unknown"
+	}
+
+	if inputMethod, mapped := lookupInputMethod(b.inputMethods, engine); mapped {
+		return inputMethod
+	}
+
+	logger.Debugf("IBus engine %s is not mapped in input_methods", engine)
+	return engine
+}
+
+// Switch switches to the IBus engine mapped to the given logical name
+func (b *IBusBackend) Switch(inputMethod string) error {
+	engine, mapped := b.inputMethods[inputMethod]
+	if !mapped || engine == "" {
+		return fmt.Errorf("no IBus engine configured for: %s", inputMethod)
+	}
+
+	return b.ibus.SetGlobalEngine(engine)
+}
+
+// ListInputMethods returns every configured input method
+func (b *IBusBackend) ListInputMethods() []string {
+	return sortedKeys(b.inputMethods)
+}
+
+// Status reports the IBus bus address in use
+func (b *IBusBackend) Status() map[string]interface{} {
+	return map[string]interface{}{
+		"ibus_address": b.ibus.GetAddress(),
+	}
+}
@@ -0,0 +1,139 @@
+package inputmethod
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"hypr-input-switcher/internal/testutil"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// fakeIBus serves the global engine part of the IBus D-Bus interface
+type fakeIBus struct {
+	mutex  sync.Mutex
+	engine string
+	props  *prop.Properties
+}
+
+// SetGlobalEngine implements org.freedesktop.IBus.SetGlobalEngine
+func (f *fakeIBus) SetGlobalEngine(engine string) *dbus.Error {
+	f.mutex.Lock()
+	f.engine = engine
+	f.mutex.Unlock()
+
+	f.props.SetMust(ibusInterface, "GlobalEngine", ibusEngineDesc(engine))
+	return nil
+}
+
+// ibusEngineDesc serializes an engine description the way IBus does, as a
+// variant wrapping the IBusEngineDesc struct
+func ibusEngineDesc(engine string) dbus.Variant {
+	return dbus.MakeVariant(struct {
+		TypeName    string
+		Attachments map[string]dbus.Variant
+		Name        string
+		LongName    string
+	}{"IBusEngineDesc", map[string]dbus.Variant{}, engine, strings.ToUpper(engine)})
+}
+
+// startFakeIBus exports a fake IBus daemon on a private bus
+func startFakeIBus(t *testing.T, engine string) (*IBus, *fakeIBus) {
+	t.Helper()
+
+	address := testutil.StartDBus(t)
+
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("failed to connect to test bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	fake := &fakeIBus{engine: engine}
+	if err := conn.Export(fake, ibusPath, ibusInterface); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.props, err = prop.Export(conn, ibusPath, prop.Map{
+		ibusInterface: {
+			"GlobalEngine": {Value: ibusEngineDesc(engine), Emit: prop.EmitFalse},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := conn.RequestName(ibusService, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("failed to own %s: %v", ibusService, err)
+	}
+
+	return NewIBus(address), fake
+}
+
+func TestEngineDescName(t *testing.T) {
+	// Structs arrive from the bus as []interface{}, wrapped in a variant
+	// once by the property and once more by IBus itself
+	desc := dbus.MakeVariant([]interface{}{"IBusEngineDesc", map[string]dbus.Variant{}, "rime", "Rime"})
+
+	tests := []struct {
+		name string
+		desc dbus.Variant
+	}{
+		{"plain", desc},
+		{"nested", dbus.MakeVariant(desc)},
+		{"double nested", dbus.MakeVariant(dbus.MakeVariant(desc))},
+	}
+
+	for _, test := range tests {
+		name, err := engineDescName(test.desc)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if name != "rime" {
+			t.Errorf("%s: got %q, want rime", test.name, name)
+		}
+	}
+}
+
+func TestEngineDescNameInvalid(t *testing.T) {
+	for _, desc := range []dbus.Variant{
+		dbus.MakeVariant("rime"),
+		dbus.MakeVariant([]interface{}{"IBusEngineDesc"}),
+		dbus.MakeVariant([]interface{}{"IBusEngineDesc", map[string]dbus.Variant{}, uint32(1)}),
+	} {
+		if name, err := engineDescName(desc); err == nil {
+			t.Errorf("engineDescName(%s) = %q, want error", desc, name)
+		}
+	}
+}
+
+func TestIBusGlobalEngineViaDBus(t *testing.T) {
+	ibus, fake := startFakeIBus(t, "xkb:us::eng")
+
+	if !ibus.IsAvailable() {
+		t.Fatal("fake IBus is not available")
+	}
+
+	if engine := ibus.getGlobalEngineViaDBus(); engine != "xkb:us::eng" {
+		t.Errorf("got engine %q, want xkb:us::eng", engine)
+	}
+
+	if err := ibus.setGlobalEngineViaDBus("rime"); err != nil {
+		t.Fatalf("SetGlobalEngine failed: %v", err)
+	}
+
+	fake.mutex.Lock()
+	engine := fake.engine
+	fake.mutex.Unlock()
+	if engine != "rime" {
+		t.Errorf("fake received engine %q, want rime", engine)
+	}
+
+	if engine := ibus.getGlobalEngineViaDBus(); engine != "rime" {
+		t.Errorf("got engine %q after switching, want rime", engine)
+	}
+}
@@ -0,0 +1,208 @@
+package inputmethod
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"hypr-input-switcher/internal/hyprland"
+	"hypr-input-switcher/pkg/logger"
+)
+
+// xkbRulesPath lists xkb layouts and variants with their descriptions,
+// which are the keymap names Hyprland reports in activelayout events
+const xkbRulesPath = "/usr/share/X11/xkb/rules/evdev.lst"
+
+// KeyboardDevice represents a keyboard from hyprctl devices -j
+type KeyboardDevice struct {
+	Address      string `json:"address"`
+	Name         string `json:"name"`
+	Layout       string `json:"layout"`
+	Variant      string `json:"variant"`
+	ActiveKeymap string `json:"active_keymap"`
+	Main         bool   `json:"main"`
+}
+
+// Layouts returns the device layouts as "code" or "code(variant)" entries
+func (d *KeyboardDevice) Layouts() []string {
+	layouts := strings.Split(d.Layout, ",")
+	variants := strings.Split(d.Variant, ",")
+
+	for i := range layouts {
+		layouts[i] = strings.TrimSpace(layouts[i])
+		if i < len(variants) && strings.TrimSpace(variants[i]) != "" {
+			layouts[i] = fmt.Sprintf("%s(%s)", layouts[i], strings.TrimSpace(variants[i]))
+		}
+	}
+
+	return layouts
+}
+
+// IndexOf returns the index of a layout code, "code(variant)" or index string
+func (d *KeyboardDevice) IndexOf(layout string) (int, bool) {
+	layouts := d.Layouts()
+
+	if index, err := strconv.Atoi(layout); err == nil {
+		return index, index >= 0 && index < len(layouts)
+	}
+
+	// Exact match first, then match on the layout code alone
+	for i, candidate := range layouts {
+		if candidate == layout {
+			return i, true
+		}
+	}
+	for i, candidate := range layouts {
+		if code, _, _ := strings.Cut(candidate, "("); code == layout {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// KeyboardLayout switches XKB layouts via Hyprland's switchxkblayout
+type KeyboardLayout struct {
+	client  *hyprland.Client
+	devices []string // device names to switch, empty for all
+
+	descriptionsOnce sync.Once
+	descriptions     map[string]string // keymap description -> "code" or "code(variant)"
+}
+
+func NewKeyboardLayout(devices []string) *KeyboardLayout {
+	return &KeyboardLayout{
+		client:  hyprland.NewClient(),
+		devices: devices,
+	}
+}
+
+// GetKeyboards returns the keyboards known to Hyprland
+func (k *KeyboardLayout) GetKeyboards() ([]KeyboardDevice, error) {
+	var devices struct {
+		Keyboards []KeyboardDevice `json:"keyboards"`
+	}
+	if err := k.client.Devices(&devices); err != nil {
+		return nil, err
+	}
+
+	return devices.Keyboards, nil
+}
+
+// GetReferenceKeyboard returns the keyboard whose layout represents the
+// current state: the first configured device, else the main keyboard
+func (k *KeyboardLayout) GetReferenceKeyboard() (*KeyboardDevice, error) {
+	keyboards, err := k.GetKeyboards()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keyboards) == 0 {
+		return nil, fmt.Errorf("no keyboards found")
+	}
+
+	if len(k.devices) > 0 {
+		for i := range keyboards {
+			if keyboards[i].Name == k.devices[0] {
+				return &keyboards[i], nil
+			}
+		}
+		return nil, fmt.Errorf("keyboard %s not found", k.devices[0])
+	}
+
+	for i := range keyboards {
+		if keyboards[i].Main {
+			return &keyboards[i], nil
+		}
+	}
+
+	return &keyboards[0], nil
+}
+
+// TracksDevice checks if events from the named keyboard affect the current state
+func (k *KeyboardLayout) TracksDevice(name string) bool {
+	if len(k.devices) == 0 {
+		return true
+	}
+
+	for _, device := range k.devices {
+		if device == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SwitchLayout switches the configured keyboards to the given layout index
+func (k *KeyboardLayout) SwitchLayout(index int) error {
+	devices := k.devices
+	if len(devices) == 0 {
+		devices = []string{"all"}
+	}
+
+	commands := make([]string, 0, len(devices))
+	for _, device := range devices {
+		logger.Debugf("Switching keyboard %s to layout index %d", device, index)
+		commands = append(commands, fmt.Sprintf("switchxkblayout %s %d", device, index))
+	}
+
+	if err := k.client.Batch(commands...); err != nil {
+		return fmt.Errorf("failed to switch layout: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveKeymap converts a keymap description such as "German (no dead keys)"
+// into "code" or "code(variant)" using the xkb rules list
+func (k *KeyboardLayout) ResolveKeymap(keymap string) (string, bool) {
+	k.descriptionsOnce.Do(k.loadDescriptions)
+
+	layout, exists := k.descriptions[keymap]
+	return layout, exists
+}
+
+// loadDescriptions parses the layout and variant sections of the xkb rules list
+func (k *KeyboardLayout) loadDescriptions() {
+	k.descriptions = make(map[string]string)
+
+	file, err := os.Open(xkbRulesPath)
+	if err != nil {
+		logger.Debugf("Failed to read xkb rules: %v", err)
+		return
+	}
+	defer file.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "!") {
+			section = strings.TrimSpace(strings.TrimPrefix(line, "!"))
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		name := fields[0]
+		description := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), name))
+
+		switch section {
+		case "layout":
+			k.descriptions[description] = name
+		case "variant":
+			// Variant descriptions are prefixed with "layout: "
+			layout, description, found := strings.Cut(description, ": ")
+			if found {
+				k.descriptions[description] = fmt.Sprintf("%s(%s)", layout, name)
+			}
+		}
+	}
+}
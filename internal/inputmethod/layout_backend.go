@@ -0,0 +1,217 @@
+package inputmethod
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"hypr-input-switcher/internal/config"
+	"hypr-input-switcher/pkg/logger"
+)
+
+// LayoutBackend switches XKB keyboard layouts instead of an input method
+// engine. Logical input methods map to a layout code ("de"), a layout with
+// variant ("de(nodeadkeys)"), a layout index ("1") or a keymap name
+// ("German") via input_methods. Unmapped targets are used as is.
+type LayoutBackend struct {
+	layout       *KeyboardLayout
+	inputMethods map[string]string // inputMethod -> layout
+
+	mutex         sync.RWMutex
+	currentKeymap string // last keymap reported by an activelayout event
+}
+
+// newLayoutBackend creates the keyboard layout backend
+func newLayoutBackend(cfg *config.Config) Backend {
+	return &LayoutBackend{
+		layout:       NewKeyboardLayout(cfg.KeyboardLayout.Devices),
+		inputMethods: cfg.InputMethods,
+	}
+}
+
+// Name returns the backend name
+func (b *LayoutBackend) Name() string {
+	return "layout"
+}
+
+// Detect checks if every configured input method resolves to a layout of
+// the reference keyboard, so IME configurations never select this backend
+func (b *LayoutBackend) Detect() bool {
+	if len(b.inputMethods) == 0 {
+		return false
+	}
+
+	keyboard, err := b.layout.GetReferenceKeyboard()
+	if err != nil {
+		return false
+	}
+
+	for _, layout := range b.inputMethods {
+		if _, found := b.resolveIndex(keyboard, layout); !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsAvailable checks if Hyprland reports a keyboard to switch
+func (b *LayoutBackend) IsAvailable() bool {
+	_, err := b.layout.GetReferenceKeyboard()
+	return err == nil
+}
+
+// HandleEvent tracks the active keymap from activelayout events
+func (b *LayoutBackend) HandleEvent(eventType, eventData string) {
+	if eventType != "activelayout" {
+		return
+	}
+
+	// eventData format: "keyboardname,layoutname"
+	device, keymap, found := strings.Cut(eventData, ",")
+	if !found || !b.layout.TracksDevice(device) {
+		return
+	}
+
+	logger.Tracef("Active layout changed on %s: %s", device, keymap)
+
+	b.mutex.Lock()
+	b.currentKeymap = keymap
+	b.mutex.Unlock()
+}
+
+// activeKeymap returns the last reported keymap, asking Hyprland if no
+// activelayout event was seen yet
+func (b *LayoutBackend) activeKeymap() (string, error) {
+	b.mutex.RLock()
+	keymap := b.currentKeymap
+	b.mutex.RUnlock()
+
+	if keymap != "" {
+		return keymap, nil
+	}
+
+	keyboard, err := b.layout.GetReferenceKeyboard()
+	if err != nil {
+		return "", err
+	}
+
+	b.mutex.Lock()
+	b.currentKeymap = keyboard.ActiveKeymap
+	b.mutex.Unlock()
+
+	return keyboard.ActiveKeymap, nil
+}
+
+// GetCurrent returns the logical name of the active layout
+func (b *LayoutBackend) GetCurrent() string {
+	keymap, err := b.activeKeymap()
+	if err != nil {
+		logger.Debugf("Failed to get keyboard layout: %v", err)
+		return "unknown"
+	}
+
+	// Keymap names can be mapped directly
+	if inputMethod, mapped := lookupInputMethod(b.inputMethods, keymap); mapped {
+		return inputMethod
+	}
+
+	layout, resolved := b.layout.ResolveKeymap(keymap)
+	if !resolved {
+		logger.Debugf("Keymap %s is not mapped in input_methods", keymap)
+		return keymap
+	}
+
+	if inputMethod, mapped := lookupInputMethod(b.inputMethods, layout); mapped {
+		return inputMethod
+	}
+
+	// Try the bare layout code for variants
+	code, _, _ := strings.Cut(layout, "(")
+	if inputMethod, mapped := lookupInputMethod(b.inputMethods, code); mapped {
+		return inputMethod
+	}
+
+	// Finally try mappings by layout index
+	if keyboard, err := b.layout.GetReferenceKeyboard(); err == nil {
+		if index, found := keyboard.IndexOf(layout); found {
+			if inputMethod, mapped := lookupInputMethod(b.inputMethods, strconv.Itoa(index)); mapped {
+				return inputMethod
+			}
+		}
+	}
+
+	return layout
+}
+
+// IsActive checks if the layout of the given logical name is active by
+// comparing layout indexes, as unmapped targets such as "1" or "de" never
+// equal the layout GetCurrent reports
+func (b *LayoutBackend) IsActive(inputMethod string) bool {
+	keymap, err := b.activeKeymap()
+	if err != nil {
+		return false
+	}
+
+	keyboard, err := b.layout.GetReferenceKeyboard()
+	if err != nil {
+		return false
+	}
+
+	return b.layoutActive(keyboard, keymap, b.targetLayout(inputMethod))
+}
+
+// layoutActive checks if a layout resolves to the same index as the keymap
+func (b *LayoutBackend) layoutActive(keyboard *KeyboardDevice, keymap, layout string) bool {
+	current, found := b.resolveIndex(keyboard, keymap)
+	if !found {
+		return false
+	}
+
+	target, found := b.resolveIndex(keyboard, layout)
+	return found && target == current
+}
+
+// targetLayout returns the layout mapped to a logical name, or the name itself
+func (b *LayoutBackend) targetLayout(inputMethod string) string {
+	if layout, mapped := b.inputMethods[inputMethod]; mapped && layout != "" {
+		return layout
+	}
+	return inputMethod
+}
+
+// Switch switches to the layout mapped to the given logical name
+func (b *LayoutBackend) Switch(inputMethod string) error {
+	layout := b.targetLayout(inputMethod)
+
+	keyboard, err := b.layout.GetReferenceKeyboard()
+	if err != nil {
+		return err
+	}
+
+	index, found := b.resolveIndex(keyboard, layout)
+	if !found {
+		return fmt.Errorf("layout %s not configured on keyboard %s (layouts: %s)", layout, keyboard.Name, strings.Join(keyboard.Layouts(), ", "))
+	}
+
+	return b.layout.SwitchLayout(index)
+}
+
+// resolveIndex finds the layout index for a layout code, index or keymap name
+func (b *LayoutBackend) resolveIndex(keyboard *KeyboardDevice, layout string) (int, bool) {
+	if index, found := keyboard.IndexOf(layout); found {
+		return index, true
+	}
+
+	if resolved, exists := b.layout.ResolveKeymap(layout); exists {
+		return keyboard.IndexOf(resolved)
+	}
+
+	return 0, false
+}
+
+// ListInputMethods returns every configured input method
+func (b *LayoutBackend) ListInputMethods() []string {
+	return sortedKeys(b.inputMethods)
+}
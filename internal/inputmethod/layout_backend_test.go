@@ -0,0 +1,51 @@
+package inputmethod
+
+import "testing"
+
+// newTestLayoutBackend creates a layout backend with fixed keymap descriptions
+func newTestLayoutBackend(inputMethods map[string]string) *LayoutBackend {
+	layout := &KeyboardLayout{}
+	layout.descriptionsOnce.Do(func() {
+		layout.descriptions = map[string]string{
+			"English (US)":          "us",
+			"German (no dead keys)": "de(nodeadkeys)",
+		}
+	})
+
+	return &LayoutBackend{layout: layout, inputMethods: inputMethods}
+}
+
+func TestLayoutActive(t *testing.T) {
+	backend := newTestLayoutBackend(map[string]string{"german": "de"})
+	keyboard := &KeyboardDevice{Layout: "us,de", Variant: ",nodeadkeys"}
+
+	tests := []struct {
+		target string
+		active bool
+	}{
+		{"1", true},
+		{"de", true},
+		{"de(nodeadkeys)", true},
+		{"German (no dead keys)", true},
+		{"german", true},
+		{"0", false},
+		{"us", false},
+		{"fr", false},
+	}
+
+	for _, test := range tests {
+		layout := backend.targetLayout(test.target)
+		if active := backend.layoutActive(keyboard, "German (no dead keys)", layout); active != test.active {
+			t.Errorf("layoutActive(%q) = %v, want %v", test.target, active, test.active)
+		}
+	}
+}
+
+func TestLayoutActiveUnknownKeymap(t *testing.T) {
+	backend := newTestLayoutBackend(nil)
+	keyboard := &KeyboardDevice{Layout: "us,de"}
+
+	if backend.layoutActive(keyboard, "Klingon", "0") {
+		t.Error("layoutActive matched an unknown keymap")
+	}
+}
@@ -0,0 +1,286 @@
+package inputmethod
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"hypr-input-switcher/pkg/logger"
+)
+
+// niriWindow is the subset of a Niri window used to describe windows
+type niriWindow struct {
+	ID          uint64 `json:"id"`
+	Title       string `json:"title"`
+	AppID       string `json:"app_id"`
+	Pid         int    `json:"pid"`
+	WorkspaceID uint64 `json:"workspace_id"`
+	IsFocused   bool   `json:"is_focused"`
+	IsFloating  bool   `json:"is_floating"`
+}
+
+// clientInfo converts a Niri window to ClientInfo
+func (w *niriWindow) clientInfo() *ClientInfo {
+	return &ClientInfo{
+		Address:  strconv.FormatUint(w.ID, 10),
+		Class:    w.AppID,
+		Title:    w.Title,
+		Floating: w.IsFloating,
+		PID:      w.Pid,
+	}
+}
+
+// niriEvent holds the events of interest from Niri's event stream. Each
+// line carries exactly one of them.
+type niriEvent struct {
+	WindowsChanged *struct {
+		Windows []niriWindow `json:"windows"`
+	} `json:"WindowsChanged"`
+	WindowOpenedOrChanged *struct {
+		Window niriWindow `json:"window"`
+	} `json:"WindowOpenedOrChanged"`
+	WindowClosed *struct {
+		ID uint64 `json:"id"`
+	} `json:"WindowClosed"`
+	WindowFocusChanged *struct {
+		ID *uint64 `json:"id"`
+	} `json:"WindowFocusChanged"`
+}
+
+// NiriSource tracks window focus through Niri's JSON event stream
+type NiriSource struct {
+	windows   map[uint64]*niriWindow
+	focusedID *uint64
+}
+
+func NewNiriSource() *NiriSource {
+	return &NiriSource{
+		windows: make(map[uint64]*niriWindow),
+	}
+}
+
+// Name returns the compositor name
+func (n *NiriSource) Name() string {
+	return "niri"
+}
+
+// IsAvailable checks if the Niri socket exists
+func (n *NiriSource) IsAvailable() bool {
+	socketPath := os.Getenv("NIRI_SOCKET")
+	if socketPath == "" {
+		logger.Error("NIRI_SOCKET not set")
+		return false
+	}
+
+	if _, err := os.Stat(socketPath); err != nil {
+		logger.Errorf("Niri socket not available: %v", err)
+		return false
+	}
+
+	return true
+}
+
+// connect opens a connection to the Niri socket
+func (n *NiriSource) connect() (net.Conn, error) {
+	socketPath := os.Getenv("NIRI_SOCKET")
+	if socketPath == "" {
+		return nil, fmt.Errorf("NIRI_SOCKET not set")
+	}
+
+	return net.Dial("unix", socketPath)
+}
+
+// request sends a single request and returns the "Ok" payload of the reply
+func (n *NiriSource) request(conn net.Conn, reader *bufio.Reader, request string) (json.RawMessage, error) {
+	if _, err := fmt.Fprintf(conn, "%q\n", request); err != nil {
+		return nil, err
+	}
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	var reply struct {
+		Ok  json.RawMessage `json:"Ok"`
+		Err *string         `json:"Err"`
+	}
+	if err := json.Unmarshal(line, &reply); err != nil {
+		return nil, fmt.Errorf("failed to parse reply: %w", err)
+	}
+
+	if reply.Err != nil {
+		return nil, fmt.Errorf("niri error: %s", *reply.Err)
+	}
+
+	return reply.Ok, nil
+}
+
+// ActiveWindow returns the currently focused window
+func (n *NiriSource) ActiveWindow() (*ClientInfo, error) {
+	conn, err := n.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	payload, err := n.request(conn, bufio.NewReader(conn), "FocusedWindow")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get focused window: %w", err)
+	}
+
+	var response struct {
+		FocusedWindow *niriWindow `json:"FocusedWindow"`
+	}
+	if err := json.Unmarshal(payload, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse focused window: %w", err)
+	}
+
+	if response.FocusedWindow == nil {
+		// Nothing focused, such as an empty workspace
+		return &ClientInfo{}, nil
+	}
+
+	return response.FocusedWindow.clientInfo(), nil
+}
+
+// Run follows the event stream until the context is cancelled
+func (n *NiriSource) Run(ctx context.Context, events chan<- WindowEvent) error {
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping input method switcher...")
+			return nil
+		default:
+		}
+
+		err := n.streamEvents(ctx, events)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		logger.Errorf("Niri event monitoring error: %v", err)
+		// Retry after delay
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// streamEvents reads events from a single event stream connection
+func (n *NiriSource) streamEvents(ctx context.Context, events chan<- WindowEvent) error {
+	conn, err := n.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock reads when the context is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	reader := bufio.NewReader(conn)
+	if _, err := n.request(conn, reader, "EventStream"); err != nil {
+		return fmt.Errorf("failed to start event stream: %w", err)
+	}
+
+	logger.Debug("Connected to Niri event stream")
+
+	// The stream starts with the full window list, so state is rebuilt
+	n.windows = make(map[uint64]*niriWindow)
+	n.focusedID = nil
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+
+		var event niriEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			logger.Warningf("Failed to parse Niri event: %v", err)
+			continue
+		}
+
+		if err := n.handleEvent(ctx, &event, events); err != nil {
+			return err
+		}
+	}
+}
+
+// handleEvent updates the window table and emits focus changes
+func (n *NiriSource) handleEvent(ctx context.Context, event *niriEvent, events chan<- WindowEvent) error {
+	switch {
+	case event.WindowsChanged != nil:
+		n.windows = make(map[uint64]*niriWindow)
+		var focusedID *uint64
+		for i := range event.WindowsChanged.Windows {
+			window := event.WindowsChanged.Windows[i]
+			n.windows[window.ID] = &window
+			if window.IsFocused {
+				focusedID = &window.ID
+			}
+		}
+		logger.Tracef("Niri windows changed: %d windows", len(n.windows))
+		return n.setFocus(ctx, focusedID, events)
+
+	case event.WindowOpenedOrChanged != nil:
+		window := event.WindowOpenedOrChanged.Window
+		previous, existed := n.windows[window.ID]
+		n.windows[window.ID] = &window
+		logger.Tracef("Niri window opened or changed: %d (%s)", window.ID, window.AppID)
+		if !window.IsFocused {
+			return nil
+		}
+
+		if n.focusedID != nil && *n.focusedID == window.ID {
+			if existed && previous.Title != window.Title {
+				return sendEvent(ctx, events, WindowEvent{Type: WindowTitleChanged, Client: window.clientInfo()})
+			}
+			return nil
+		}
+		return n.setFocus(ctx, &window.ID, events)
+
+	case event.WindowClosed != nil:
+		logger.Tracef("Niri window closed: %d", event.WindowClosed.ID)
+		delete(n.windows, event.WindowClosed.ID)
+		closed := &ClientInfo{Address: strconv.FormatUint(event.WindowClosed.ID, 10)}
+		return sendEvent(ctx, events, WindowEvent{Type: WindowClosed, Client: closed})
+
+	case event.WindowFocusChanged != nil:
+		return n.setFocus(ctx, event.WindowFocusChanged.ID, events)
+	}
+
+	return nil
+}
+
+// setFocus emits a focus event if the focused window changed
+func (n *NiriSource) setFocus(ctx context.Context, id *uint64, events chan<- WindowEvent) error {
+	if id == nil {
+		n.focusedID = nil
+		return nil
+	}
+
+	if n.focusedID != nil && *n.focusedID == *id {
+		return nil
+	}
+
+	window, exists := n.windows[*id]
+	if !exists {
+		logger.Tracef("Focused Niri window %d not known yet", *id)
+		return nil
+	}
+
+	focusedID := *id
+	n.focusedID = &focusedID
+
+	logger.Tracef("Niri window focused: %d (%s)", window.ID, window.AppID)
+	return sendEvent(ctx, events, WindowEvent{Type: WindowFocused, Client: window.clientInfo()})
+}
@@ -0,0 +1,141 @@
+package inputmethod
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startFakeNiri listens on a Niri socket and serves each connection with
+// the given handler, which receives the first request line
+func startFakeNiri(t *testing.T, handle func(t *testing.T, conn net.Conn, request string)) {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "niri.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	t.Setenv("NIRI_SOCKET", socketPath)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				request, err := reader.ReadString('\n')
+				if err != nil {
+					t.Errorf("failed to read request: %v", err)
+					return
+				}
+				handle(t, conn, request)
+
+				// Keep the connection open until the source disconnects
+				reader.ReadString('\n')
+			}()
+		}
+	}()
+}
+
+func TestNiriActiveWindow(t *testing.T) {
+	startFakeNiri(t, func(t *testing.T, conn net.Conn, request string) {
+		if request != "\"FocusedWindow\"\n" {
+			t.Errorf("got request %q", request)
+			return
+		}
+		conn.Write([]byte(`{"Ok":{"FocusedWindow":{"id":7,"title":"Mozilla Firefox","app_id":"firefox","pid":42,"is_focused":true,"is_floating":true}}}` + "\n"))
+	})
+
+	client, err := NewNiriSource().ActiveWindow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.Address != "7" || client.Class != "firefox" || client.Title != "Mozilla Firefox" || client.PID != 42 || !client.Floating {
+		t.Errorf("unexpected client: %+v", client)
+	}
+}
+
+func TestNiriEvents(t *testing.T) {
+	startFakeNiri(t, func(t *testing.T, conn net.Conn, request string) {
+		if request != "\"EventStream\"\n" {
+			t.Errorf("got request %q", request)
+			return
+		}
+
+		for _, line := range []string{
+			`{"Ok":"Handled"}`,
+			`{"WindowsChanged":{"windows":[{"id":1,"title":"Mozilla Firefox","app_id":"firefox","is_focused":true},{"id":2,"title":"zsh","app_id":"kitty"}]}}`,
+			`{"WindowFocusChanged":{"id":2}}`,
+			// Refocusing the focused window and focusing unknown windows emit nothing
+			`{"WindowFocusChanged":{"id":2}}`,
+			`{"WindowFocusChanged":{"id":99}}`,
+			`not json`,
+			`{"WindowOpenedOrChanged":{"window":{"id":2,"title":"vim","app_id":"kitty","is_focused":true}}}`,
+			// Background windows opening or changing emit nothing
+			`{"WindowOpenedOrChanged":{"window":{"id":1,"title":"GitHub","app_id":"firefox"}}}`,
+			`{"WindowOpenedOrChanged":{"window":{"id":3,"title":"Files","app_id":"org.gnome.Nautilus","is_focused":true,"is_floating":true}}}`,
+			`{"WindowClosed":{"id":3}}`,
+			`{"WindowFocusChanged":{"id":null}}`,
+			`{"WindowFocusChanged":{"id":1}}`,
+		} {
+			if _, err := conn.Write([]byte(line + "\n")); err != nil {
+				t.Errorf("failed to write event: %v", err)
+				return
+			}
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan WindowEvent)
+	done := make(chan error, 1)
+	go func() { done <- NewNiriSource().Run(ctx, events) }()
+
+	expected := []struct {
+		eventType WindowEventType
+		address   string
+		class     string
+		title     string
+	}{
+		{WindowFocused, "1", "firefox", "Mozilla Firefox"},
+		{WindowFocused, "2", "kitty", "zsh"},
+		{WindowTitleChanged, "2", "kitty", "vim"},
+		{WindowFocused, "3", "org.gnome.Nautilus", "Files"},
+		{WindowClosed, "3", "", ""},
+		{WindowFocused, "1", "firefox", "GitHub"},
+	}
+
+	for _, want := range expected {
+		select {
+		case event := <-events:
+			if event.Type != want.eventType || event.Client == nil {
+				t.Fatalf("got event %+v, want type %d", event, want.eventType)
+			}
+			if event.Client.Address != want.address || event.Client.Class != want.class || event.Client.Title != want.title {
+				t.Errorf("event %d: got client %+v, want %s %s %q", want.eventType, event.Client, want.address, want.class, want.title)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %d", want.eventType)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+}
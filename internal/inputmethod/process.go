@@ -0,0 +1,153 @@
+package inputmethod
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// procDir is the mount point of procfs, replaced by tests with fixtures
+var procDir = "/proc"
+
+// ProcessInfo describes the process owning a window
+type ProcessInfo struct {
+	PID        int          `json:"pid"`
+	Exe        string       `json:"exe"`
+	Cmdline    string       `json:"cmdline"`
+	AppID      string       `json:"app_id,omitempty"`     // Flatpak application ID or Snap name
+	Foreground *ProcessInfo `json:"foreground,omitempty"` // foreground job of a terminal
+}
+
+// procStat holds the fields of /proc/<pid>/stat used to find foreground jobs
+type procStat struct {
+	PPID  int
+	TTY   int
+	TPGID int
+}
+
+// LookupProcess reads the process of a window, its sandbox identity and, for
+// terminals, the foreground process of the terminal's shell
+func LookupProcess(pid int) (*ProcessInfo, error) {
+	if pid <= 0 {
+		return nil, fmt.Errorf("invalid pid: %d", pid)
+	}
+
+	process, err := readProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	process.AppID = readSandboxID(pid)
+
+	if foregroundPID := findForegroundPID(pid); foregroundPID > 0 && foregroundPID != pid {
+		if foreground, err := readProcess(foregroundPID); err == nil {
+			process.Foreground = foreground
+		}
+	}
+
+	return process, nil
+}
+
+// readProcess reads the executable and command line of a process
+func readProcess(pid int) (*ProcessInfo, error) {
+	cmdline, err := os.ReadFile(procPath(pid, "cmdline"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process %d: %w", pid, err)
+	}
+
+	// The exe link is unreadable for processes of other users
+	exe, _ := os.Readlink(procPath(pid, "exe"))
+
+	return &ProcessInfo{
+		PID:     pid,
+		Exe:     strings.TrimSuffix(exe, " (deleted)"),
+		Cmdline: strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " ")),
+	}, nil
+}
+
+// findForegroundPID returns the foreground process group leader on the
+// terminal of the process or, for terminal emulators, of its children.
+// If several children have a terminal, such as terminal tabs, the newest
+// one is used.
+func findForegroundPID(pid int) int {
+	if stat, err := readProcStat(pid); err == nil && stat.TTY != 0 && stat.TPGID > 0 {
+		return stat.TPGID
+	}
+
+	children := childPIDs(pid)
+	sort.Sort(sort.Reverse(sort.IntSlice(children)))
+
+	for _, child := range children {
+		if stat, err := readProcStat(child); err == nil && stat.TTY != 0 && stat.TPGID > 0 {
+			return stat.TPGID
+		}
+	}
+
+	return 0
+}
+
+// readProcStat parses /proc/<pid>/stat
+func readProcStat(pid int) (*procStat, error) {
+	data, err := os.ReadFile(procPath(pid, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The command name may contain spaces and parentheses, so fields are
+	// counted from the last closing parenthesis
+	end := strings.LastIndexByte(string(data), ')')
+	if end < 0 {
+		return nil, fmt.Errorf("malformed stat for process %d", pid)
+	}
+
+	// Fields after the command: state ppid pgrp session tty_nr tpgid ...
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 6 {
+		return nil, fmt.Errorf("malformed stat for process %d", pid)
+	}
+
+	var stat procStat
+	stat.PPID, _ = strconv.Atoi(fields[1])
+	stat.TTY, _ = strconv.Atoi(fields[4])
+	stat.TPGID, _ = strconv.Atoi(fields[5])
+	return &stat, nil
+}
+
+// childPIDs returns the direct children of a process
+func childPIDs(pid int) []int {
+	// Fast path, available with CONFIG_PROC_CHILDREN
+	if data, err := os.ReadFile(procPath(pid, "task", strconv.Itoa(pid), "children")); err == nil {
+		var children []int
+		for _, field := range strings.Fields(string(data)) {
+			if child, err := strconv.Atoi(field); err == nil {
+				children = append(children, child)
+			}
+		}
+		return children
+	}
+
+	// Fallback to scanning all processes
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil
+	}
+
+	var children []int
+	for _, entry := range entries {
+		child, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if stat, err := readProcStat(child); err == nil && stat.PPID == pid {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// procPath joins a path below /proc/<pid>
+func procPath(pid int, elements ...string) string {
+	return filepath.Join(append([]string{procDir, strconv.Itoa(pid)}, elements...)...)
+}
@@ -0,0 +1,160 @@
+package inputmethod
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useProcFixture replaces /proc with a directory holding the given files,
+// keyed by their path below /proc. Symlink targets are given as "-> target".
+func useProcFixture(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if target, isLink := cutLinkTarget(content); isLink {
+			if err := os.Symlink(target, path); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previous := procDir
+	procDir = root
+	t.Cleanup(func() { procDir = previous })
+}
+
+// cutLinkTarget returns the target of a "-> target" fixture entry
+func cutLinkTarget(content string) (string, bool) {
+	if len(content) > 3 && content[:3] == "-> " {
+		return content[3:], true
+	}
+	return "", false
+}
+
+func TestReadProcStat(t *testing.T) {
+	tests := []struct {
+		name  string
+		stat  string
+		want  procStat
+		valid bool
+	}{
+		{"plain", "100 (zsh) S 90 100 100 34816 120 4194304", procStat{PPID: 90, TTY: 34816, TPGID: 120}, true},
+		{"spaces in command", "100 (Web Content) S 90 100 100 0 -1 4194304", procStat{PPID: 90, TPGID: -1}, true},
+		{"parentheses in command", "100 (a) b (c)) S 90 100 100 34817 130 0", procStat{PPID: 90, TTY: 34817, TPGID: 130}, true},
+		{"no command", "100 S 90 100 100 0 -1", procStat{}, false},
+		{"truncated", "100 (zsh) S 90 100", procStat{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useProcFixture(t, map[string]string{"100/stat": test.stat})
+
+			stat, err := readProcStat(100)
+			if !test.valid {
+				if err == nil {
+					t.Errorf("got %+v, want an error", stat)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *stat != test.want {
+				t.Errorf("got %+v, want %+v", *stat, test.want)
+			}
+		})
+	}
+}
+
+func TestReadProcess(t *testing.T) {
+	useProcFixture(t, map[string]string{
+		"100/cmdline": "/usr/bin/nvim\x00--clean\x00notes.md\x00",
+		"100/exe":     "-> /usr/bin/nvim (deleted)",
+		"200/cmdline": "kitty\x00",
+	})
+
+	process, err := readProcess(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if process.Exe != "/usr/bin/nvim" || process.Cmdline != "/usr/bin/nvim --clean notes.md" {
+		t.Errorf("unexpected process: %+v", process)
+	}
+
+	// Unreadable exe links leave the executable empty
+	if process, err := readProcess(200); err != nil || process.Exe != "" || process.Cmdline != "kitty" {
+		t.Errorf("got %+v, %v", process, err)
+	}
+
+	if _, err := readProcess(300); err == nil {
+		t.Error("missing process did not fail")
+	}
+}
+
+func TestLookupProcessForeground(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			// The terminal's newest shell child runs nvim in the foreground
+			name: "children file",
+			files: map[string]string{
+				"100/task/100/children": "110 120",
+				"110/stat":              "110 (zsh) S 100 110 110 34816 110 0",
+				"120/stat":              "120 (zsh) S 100 120 120 34817 130 0",
+				"130/cmdline":           "nvim\x00",
+			},
+			want: "nvim",
+		},
+		{
+			name: "process scan",
+			files: map[string]string{
+				"110/stat":    "110 (zsh) S 100 110 110 34816 140 0",
+				"140/cmdline": "htop\x00",
+				"150/stat":    "150 (other) S 1 150 150 0 -1 0",
+			},
+			want: "htop",
+		},
+		{
+			name: "no terminal",
+			files: map[string]string{
+				"100/task/100/children": "110",
+				"110/stat":              "110 (helper) S 100 110 110 0 -1 0",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.files["100/cmdline"] = "kitty\x00"
+			test.files["100/stat"] = "100 (kitty) S 1 100 100 0 -1 0"
+			useProcFixture(t, test.files)
+
+			process, err := LookupProcess(100)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			foreground := ""
+			if process.Foreground != nil {
+				foreground = process.Foreground.Cmdline
+			}
+			if foreground != test.want {
+				t.Errorf("foreground = %q, want %q", foreground, test.want)
+			}
+		})
+	}
+}
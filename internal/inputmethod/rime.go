@@ -26,7 +26,6 @@ func (r *Rime) IsAvailable() bool {
 	if err != nil {
 		return false
 	}
-	defer conn.Close()
 
 	obj := conn.Object("org.fcitx.Fcitx5", "/rime")
 	var schemas []string
@@ -41,7 +40,6 @@ func (r *Rime) GetCurrentSchema() string {
 		logger.Debugf("Failed to connect to session bus: %v", err)
 		return "unknown"
 	}
-	defer conn.Close()
 
 	obj := conn.Object("org.fcitx.Fcitx5", "/rime")
 	var currentSchema string
@@ -56,11 +54,12 @@ func (r *Rime) GetCurrentSchema() string {
 	return currentSchema
 }
 
-// GetCurrentInputMethod returns the input method type based on current schema
-func (r *Rime) GetCurrentInputMethod(defaultIM string) string {
+// GetCurrentInputMethod returns the input method type based on current schema,
+// or fallback if the schema is unknown or not configured
+func (r *Rime) GetCurrentInputMethod(fallback string) string {
 	currentSchema := r.GetCurrentSchema()
 	if currentSchema == "unknown" {
-		return defaultIM
+		return fallback
 	}
 
 	// Return corresponding input method type based on schema name
@@ -70,7 +69,7 @@ func (r *Rime) GetCurrentInputMethod(defaultIM string) string {
 		}
 	}
 
-	return defaultIM
+	return fallback
 }
 
 // SwitchSchema switches to specified schema
@@ -98,7 +97,6 @@ func (r *Rime) switchSchemaViaDBus(schema string) error {
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
 
 	obj := conn.Object("org.fcitx.Fcitx5", "/rime")
 	call := obj.Call("org.fcitx.Fcitx.Rime1.SetSchema", 0, schema)
@@ -131,7 +129,6 @@ func (r *Rime) GetAvailableSchemas() ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close()
 
 	obj := conn.Object("org.fcitx.Fcitx5", "/rime")
 	var schemas []string
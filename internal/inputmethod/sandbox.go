@@ -0,0 +1,78 @@
+package inputmethod
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+)
+
+// readSandboxID returns the Flatpak application ID or Snap name of a
+// sandboxed process, or an empty string for unsandboxed processes
+func readSandboxID(pid int) string {
+	if appID := readFlatpakID(pid); appID != "" {
+		return appID
+	}
+
+	return readSnapName(pid)
+}
+
+// readFlatpakID reads the application name from the .flatpak-info file
+// Flatpak places at the root of the sandbox
+func readFlatpakID(pid int) string {
+	file, err := os.Open(procPath(pid, "root", ".flatpak-info"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.Trim(line, "[]")
+			continue
+		}
+
+		if section != "Application" {
+			continue
+		}
+
+		if key, value, found := strings.Cut(line, "="); found && strings.TrimSpace(key) == "name" {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
+
+// readSnapName reads the snap name from the process cgroup, falling back to
+// the SNAP_NAME environment variable
+func readSnapName(pid int) string {
+	// Snap apps run in scopes like "snap.firefox.firefox-1234.scope"
+	if data, err := os.ReadFile(procPath(pid, "cgroup")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			for _, element := range strings.Split(line, "/") {
+				if rest, found := strings.CutPrefix(element, "snap."); found {
+					if name, _, found := strings.Cut(rest, "."); found && name != "" {
+						return name
+					}
+				}
+			}
+		}
+	}
+
+	environ, err := os.ReadFile(procPath(pid, "environ"))
+	if err != nil {
+		return ""
+	}
+
+	for _, variable := range bytes.Split(environ, []byte{0}) {
+		if name, found := bytes.CutPrefix(variable, []byte("SNAP_NAME=")); found {
+			return string(name)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,72 @@
+package inputmethod
+
+import "testing"
+
+func TestReadSandboxID(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "flatpak",
+			files: map[string]string{
+				"100/root/.flatpak-info": "[Instance]\nname=ignored\n\n[Application]\nname = org.mozilla.firefox\nruntime=runtime/org.freedesktop.Platform\n",
+			},
+			want: "org.mozilla.firefox",
+		},
+		{
+			name: "flatpak without application",
+			files: map[string]string{
+				"100/root/.flatpak-info": "[Instance]\nname=org.example.App\n",
+			},
+		},
+		{
+			name: "snap cgroup v2",
+			files: map[string]string{
+				"100/cgroup": "0::/user.slice/user-1000.slice/user@1000.service/app.slice/snap.firefox.firefox-2d1b.scope\n",
+			},
+			want: "firefox",
+		},
+		{
+			name: "snap cgroup v1",
+			files: map[string]string{
+				"100/cgroup": "12:pids:/user.slice\n1:name=systemd:/user.slice/snap.telegram-desktop.telegram-desktop.a1b2.scope\n",
+			},
+			want: "telegram-desktop",
+		},
+		{
+			name: "snap environment",
+			files: map[string]string{
+				"100/cgroup":  "0::/user.slice/app.slice/app-kitty.scope\n",
+				"100/environ": "HOME=/home/user\x00SNAP_NAME=code\x00SNAP=/snap/code/1\x00",
+			},
+			want: "code",
+		},
+		{
+			name: "flatpak wins over snap",
+			files: map[string]string{
+				"100/root/.flatpak-info": "[Application]\nname=org.gnome.Gedit\n",
+				"100/cgroup":             "0::/snap.gedit.gedit-1.scope\n",
+			},
+			want: "org.gnome.Gedit",
+		},
+		{
+			name: "unsandboxed",
+			files: map[string]string{
+				"100/cgroup":  "0::/user.slice/app.slice/snap.scope\n",
+				"100/environ": "HOME=/home/user\x00SNAP_NAMES=x\x00",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useProcFixture(t, test.files)
+
+			if appID := readSandboxID(100); appID != test.want {
+				t.Errorf("got %q, want %q", appID, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package inputmethod
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"hypr-input-switcher/pkg/logger"
+)
+
+// i3 IPC message types used by the Sway source
+const (
+	i3IPCMagic           = "i3-ipc"
+	i3IPCSubscribe       = 2
+	i3IPCGetTree         = 4
+	i3IPCEventWindow     = 0x80000003
+	i3IPCHeaderSize      = len(i3IPCMagic) + 8
+	i3IPCMaxPayloadBytes = 64 << 20
+)
+
+// swayNode is the subset of an i3/Sway tree node used to describe windows
+type swayNode struct {
+	ID               int64      `json:"id"`
+	Name             string     `json:"name"`
+	Type             string     `json:"type"`
+	Focused          bool       `json:"focused"`
+	AppID            string     `json:"app_id"`
+	Pid              int        `json:"pid"`
+	Shell            string     `json:"shell"`
+	FullscreenMode   int        `json:"fullscreen_mode"`
+	Sticky           bool       `json:"sticky"`
+	Nodes            []swayNode `json:"nodes"`
+	FloatingNodes    []swayNode `json:"floating_nodes"`
+	WindowProperties struct {
+		Class    string `json:"class"`
+		Instance string `json:"instance"`
+		Title    string `json:"title"`
+	} `json:"window_properties"`
+}
+
+// swayWindowEvent is the payload of an i3/Sway window event
+type swayWindowEvent struct {
+	Change    string   `json:"change"`
+	Container swayNode `json:"container"`
+}
+
+// SwaySource tracks window focus through the i3/Sway IPC protocol
+type SwaySource struct{}
+
+func NewSwaySource() *SwaySource {
+	return &SwaySource{}
+}
+
+// Name returns the compositor name
+func (w *SwaySource) Name() string {
+	return "sway"
+}
+
+// IsAvailable checks if the Sway IPC socket exists
+func (w *SwaySource) IsAvailable() bool {
+	socketPath := w.getSocketPath()
+	if socketPath == "" {
+		logger.Error("Sway IPC socket not found")
+		return false
+	}
+
+	if _, err := os.Stat(socketPath); err != nil {
+		logger.Errorf("Sway IPC socket not available: %v", err)
+		return false
+	}
+
+	return true
+}
+
+// getSocketPath returns the Sway IPC socket path
+func (w *SwaySource) getSocketPath() string {
+	// Try environment variables first
+	for _, env := range []string{"SWAYSOCK", "I3SOCK"} {
+		if socketPath := os.Getenv(env); socketPath != "" {
+			logger.Tracef("Found %s: %s", env, socketPath)
+			return socketPath
+		}
+	}
+
+	// Fallback to asking the compositor
+	for _, command := range []string{"sway", "i3"} {
+		output, err := exec.Command(command, "--get-socketpath").Output()
+		if err == nil {
+			if socketPath := strings.TrimSpace(string(output)); socketPath != "" {
+				return socketPath
+			}
+		}
+	}
+
+	return ""
+}
+
+// ActiveWindow returns the currently focused window from the layout tree
+func (w *SwaySource) ActiveWindow() (*ClientInfo, error) {
+	conn, err := w.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := writeI3Message(conn, i3IPCGetTree, nil); err != nil {
+		return nil, fmt.Errorf("failed to request tree: %w", err)
+	}
+
+	_, payload, err := readI3Message(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree: %w", err)
+	}
+
+	var root swayNode
+	if err := json.Unmarshal(payload, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse tree: %w", err)
+	}
+
+	focused := findFocusedSwayNode(&root)
+	if focused == nil {
+		// Nothing focused, such as an empty workspace
+		return &ClientInfo{}, nil
+	}
+
+	return focused.clientInfo(), nil
+}
+
+// findFocusedSwayNode returns the focused window in the tree, if any
+func findFocusedSwayNode(node *swayNode) *swayNode {
+	if node.Focused && (node.Type == "con" || node.Type == "floating_con") {
+		return node
+	}
+
+	for _, children := range [][]swayNode{node.Nodes, node.FloatingNodes} {
+		for i := range children {
+			if focused := findFocusedSwayNode(&children[i]); focused != nil {
+				return focused
+			}
+		}
+	}
+
+	return nil
+}
+
+// clientInfo converts a Sway container to ClientInfo. Native Wayland windows
+// report an app_id, XWayland windows an X11 class.
+func (n *swayNode) clientInfo() *ClientInfo {
+	class := n.AppID
+	if class == "" {
+		class = n.WindowProperties.Class
+	}
+
+	// Sway reports workspace (1) and global (2) fullscreen
+	fullscreen := FullscreenNone
+	if n.FullscreenMode != 0 {
+		fullscreen = FullscreenFull
+	}
+
+	return &ClientInfo{
+		Address:    strconv.FormatInt(n.ID, 10),
+		Class:      class,
+		Title:      n.Name,
+		PID:        n.Pid,
+		XWayland:   n.Shell == "xwayland",
+		Floating:   n.Type == "floating_con",
+		Fullscreen: fullscreen,
+		Pinned:     n.Sticky,
+	}
+}
+
+// Run subscribes to window events until the context is cancelled
+func (w *SwaySource) Run(ctx context.Context, events chan<- WindowEvent) error {
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping input method switcher...")
+			return nil
+		default:
+		}
+
+		err := w.subscribe(ctx, events)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		logger.Errorf("Sway event monitoring error: %v", err)
+		// Retry after delay
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// subscribe reads window events from a single IPC connection
+func (w *SwaySource) subscribe(ctx context.Context, events chan<- WindowEvent) error {
+	conn, err := w.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock reads when the context is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := writeI3Message(conn, i3IPCSubscribe, []byte(`["window"]`)); err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	_, payload, err := readI3Message(conn)
+	if err != nil {
+		return fmt.Errorf("failed to read subscribe reply: %w", err)
+	}
+
+	var reply struct {
+		Success bool `json:"success"`
+	}
+	if err := json.Unmarshal(payload, &reply); err != nil || !reply.Success {
+		return fmt.Errorf("subscribe rejected: %s", string(payload))
+	}
+
+	logger.Debug("Subscribed to Sway window events")
+
+	for {
+		messageType, payload, err := readI3Message(conn)
+		if err != nil {
+			return err
+		}
+
+		if messageType != i3IPCEventWindow {
+			continue
+		}
+
+		var event swayWindowEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			logger.Warningf("Failed to parse Sway window event: %v", err)
+			continue
+		}
+
+		logger.Tracef("Received Sway window event: %s (id: %d)", event.Change, event.Container.ID)
+
+		switch {
+		case event.Change == "focus":
+			if err := sendEvent(ctx, events, WindowEvent{Type: WindowFocused, Client: event.Container.clientInfo()}); err != nil {
+				return err
+			}
+		case event.Change == "title" && event.Container.Focused:
+			if err := sendEvent(ctx, events, WindowEvent{Type: WindowTitleChanged, Client: event.Container.clientInfo()}); err != nil {
+				return err
+			}
+		case event.Change == "fullscreen_mode" && event.Container.Focused:
+			if err := sendEvent(ctx, events, WindowEvent{Type: WindowStateChanged, Client: event.Container.clientInfo()}); err != nil {
+				return err
+			}
+		case event.Change == "close":
+			if err := sendEvent(ctx, events, WindowEvent{Type: WindowClosed, Client: event.Container.clientInfo()}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// connect opens a connection to the Sway IPC socket
+func (w *SwaySource) connect() (net.Conn, error) {
+	socketPath := w.getSocketPath()
+	if socketPath == "" {
+		return nil, fmt.Errorf("sway IPC socket not found")
+	}
+
+	return net.Dial("unix", socketPath)
+}
+
+// writeI3Message writes an i3 IPC message: magic, payload length, type, payload
+func writeI3Message(conn net.Conn, messageType uint32, payload []byte) error {
+	message := make([]byte, i3IPCHeaderSize+len(payload))
+	copy(message, i3IPCMagic)
+	binary.LittleEndian.PutUint32(message[len(i3IPCMagic):], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(message[len(i3IPCMagic)+4:], messageType)
+	copy(message[i3IPCHeaderSize:], payload)
+
+	_, err := conn.Write(message)
+	return err
+}
+
+// readI3Message reads a single i3 IPC message
+func readI3Message(conn net.Conn) (uint32, []byte, error) {
+	header := make([]byte, i3IPCHeaderSize)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, nil, err
+	}
+
+	if string(header[:len(i3IPCMagic)]) != i3IPCMagic {
+		return 0, nil, fmt.Errorf("invalid IPC magic: %q", header[:len(i3IPCMagic)])
+	}
+
+	length := binary.LittleEndian.Uint32(header[len(i3IPCMagic):])
+	messageType := binary.LittleEndian.Uint32(header[len(i3IPCMagic)+4:])
+
+	if length > i3IPCMaxPayloadBytes {
+		return 0, nil, fmt.Errorf("IPC payload too large: %d bytes", length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return messageType, payload, nil
+}
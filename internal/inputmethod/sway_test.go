@@ -0,0 +1,145 @@
+package inputmethod
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startFakeSway listens on a Sway IPC socket and serves each connection with
+// the given handler
+func startFakeSway(t *testing.T, handle func(t *testing.T, conn net.Conn)) {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "sway-ipc.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	t.Setenv("SWAYSOCK", socketPath)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(t, conn)
+			}()
+		}
+	}()
+}
+
+// expectI3Message reads a message and checks its type and payload
+func expectI3Message(t *testing.T, conn net.Conn, messageType uint32, payload string) bool {
+	gotType, gotPayload, err := readI3Message(conn)
+	if err != nil {
+		t.Errorf("failed to read message: %v", err)
+		return false
+	}
+	if gotType != messageType || string(gotPayload) != payload {
+		t.Errorf("got message %d %q, want %d %q", gotType, gotPayload, messageType, payload)
+		return false
+	}
+	return true
+}
+
+func TestSwayActiveWindow(t *testing.T) {
+	startFakeSway(t, func(t *testing.T, conn net.Conn) {
+		if !expectI3Message(t, conn, i3IPCGetTree, "") {
+			return
+		}
+		writeI3Message(conn, i3IPCGetTree, []byte(`{"id":1,"type":"root","nodes":[
+			{"id":2,"type":"workspace","nodes":[{"id":3,"type":"con","app_id":"foot","name":"shell"}],
+			 "floating_nodes":[{"id":4,"type":"floating_con","focused":true,"shell":"xwayland","pid":42,
+			  "name":"Chat","window_properties":{"class":"Slack"}}]}]}`))
+	})
+
+	client, err := NewSwaySource().ActiveWindow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.Address != "4" || client.Class != "Slack" || client.Title != "Chat" || client.PID != 42 ||
+		!client.XWayland || !client.Floating {
+		t.Errorf("unexpected client: %+v", client)
+	}
+}
+
+func TestSwayEvents(t *testing.T) {
+	startFakeSway(t, func(t *testing.T, conn net.Conn) {
+		if !expectI3Message(t, conn, i3IPCSubscribe, `["window"]`) {
+			return
+		}
+		writeI3Message(conn, i3IPCSubscribe, []byte(`{"success":true}`))
+
+		for _, event := range []struct {
+			messageType uint32
+			payload     string
+		}{
+			{i3IPCEventWindow, `{"change":"focus","container":{"id":7,"type":"con","focused":true,"app_id":"firefox","name":"Mozilla Firefox"}}`},
+			// Title changes of background windows and other event types are ignored
+			{i3IPCEventWindow, `{"change":"title","container":{"id":8,"type":"con","app_id":"foot","name":"vim"}}`},
+			{0x80000000, `{"change":"focus"}`},
+			{i3IPCEventWindow, `{"change":"title","container":{"id":7,"type":"con","focused":true,"app_id":"firefox","name":"GitHub"}}`},
+			{i3IPCEventWindow, `{"change":"fullscreen_mode","container":{"id":7,"type":"con","focused":true,"app_id":"firefox","fullscreen_mode":1}}`},
+			{i3IPCEventWindow, `{"change":"close","container":{"id":7,"type":"con","app_id":"firefox"}}`},
+		} {
+			if err := writeI3Message(conn, event.messageType, []byte(event.payload)); err != nil {
+				t.Errorf("failed to write event: %v", err)
+				return
+			}
+		}
+
+		// Keep the connection open until the source disconnects
+		readI3Message(conn)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan WindowEvent)
+	done := make(chan error, 1)
+	go func() { done <- NewSwaySource().Run(ctx, events) }()
+
+	expected := []struct {
+		eventType  WindowEventType
+		title      string
+		fullscreen FullscreenMode
+	}{
+		{WindowFocused, "Mozilla Firefox", FullscreenNone},
+		{WindowTitleChanged, "GitHub", FullscreenNone},
+		{WindowStateChanged, "", FullscreenFull},
+		{WindowClosed, "", FullscreenNone},
+	}
+
+	for _, want := range expected {
+		select {
+		case event := <-events:
+			if event.Type != want.eventType || event.Client == nil {
+				t.Fatalf("got event %+v, want type %d", event, want.eventType)
+			}
+			if event.Client.Address != "7" || event.Client.Class != "firefox" ||
+				event.Client.Title != want.title || event.Client.Fullscreen != want.fullscreen {
+				t.Errorf("event %d: unexpected client %+v", want.eventType, event.Client)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %d", want.eventType)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+}
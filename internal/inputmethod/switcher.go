@@ -1,37 +1,112 @@
 package inputmethod
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
-	"net"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"hypr-input-switcher/internal/config"
+	"hypr-input-switcher/internal/state"
 	"hypr-input-switcher/pkg/logger"
 )
 
 type Switcher struct {
 	currentClient *ClientInfo
+	currentRule   *config.ClientRule // rule matching the current client, if any
 	currentIM     string
 	config        *config.Config
-	fcitx5        *Fcitx5
-	rime          *Rime
-	notifier      interface {
+	backend       Backend
+	source        WindowSource
+	windowIMs     map[string]string // input method per window address in window memory mode
+	previousIM    string            // input method of the previously focused window
+	classMemory   *state.Store      // learned input method per window class
+	rules         *config.RuleSet   // compiled client rules
+	passwordRules *config.RuleSet   // credential prompt rules, nil unless password_safe is enabled
+	gameRules     *config.RuleSet   // game rules, nil unless game_mode is enabled
+
+	credentialPrompt    bool   // a credential prompt has focus
+	promptReturnAddress string // window focused before the credential prompt
+	gameMode            bool   // a game has focus, switching and notifications are suspended
+	launcher            string // namespace of the open launcher layer, if any
+	launcherReturnIM    string // input method from before the launcher opened
+	paused              bool   // automatic switching is paused on request
+
+	// Guards the state above against control requests, which arrive from
+	// other goroutines than the event loop
+	mutex sync.Mutex
+
+	notifier interface {
 		ShowInputMethodSwitch(inputMethod string, clientInfo *config.WindowInfo)
 	}
+	listener func(change Change) // called after every input method switch
 }
 
 type ClientInfo struct {
-	Address string `json:"address"`
-	Class   string `json:"class"`
-	Title   string `json:"title"`
+	Address   string        `json:"address"`
+	Class     string        `json:"class"`
+	Title     string        `json:"title"`
+	Workspace WorkspaceInfo `json:"workspace"`
+	MonitorID int           `json:"monitor"`
+	Monitor   string        `json:"monitor_name"` // resolved by the window source
+
+	InitialClass string         `json:"initialClass"`
+	InitialTitle string         `json:"initialTitle"`
+	XWayland     bool           `json:"xwayland"`
+	Floating     bool           `json:"floating"`
+	Fullscreen   FullscreenMode `json:"fullscreen"`
+	Pinned       bool           `json:"pinned"`
+	Grouped      []string       `json:"grouped"` // addresses of the windows in the group
+
+	PID     int          `json:"pid"`
+	Process *ProcessInfo `json:"process,omitempty"` // resolved for process rules
+
+	partial bool // built from an event and missing properties
+}
+
+// WorkspaceInfo identifies the workspace a window is on
+type WorkspaceInfo struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// FullscreenMode is a window's fullscreen state. Hyprland reports it as a
+// number, while older versions report a boolean.
+type FullscreenMode int
+
+// Fullscreen modes, as reported by Hyprland; a window can be both
+const (
+	FullscreenNone      FullscreenMode = 0
+	FullscreenMaximized FullscreenMode = 1
+	FullscreenFull      FullscreenMode = 2
+)
+
+// IsFullscreen checks if the window covers its monitor, as opposed to only
+// being maximized
+func (f FullscreenMode) IsFullscreen() bool {
+	return f&FullscreenFull != 0
+}
+
+// UnmarshalJSON accepts both a number and a boolean
+func (f *FullscreenMode) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true":
+		*f = FullscreenFull
+		return nil
+	case "false", "null":
+		*f = 0
+		return nil
+	}
+
+	var mode int
+	if err := json.Unmarshal(data, &mode); err != nil {
+		return fmt.Errorf("invalid fullscreen state: %s", data)
+	}
+	*f = FullscreenMode(mode)
+	return nil
 }
 
 func NewSwitcher(cfg *config.Config) *Switcher {
@@ -39,12 +114,44 @@ func NewSwitcher(cfg *config.Config) *Switcher {
 		currentClient: &ClientInfo{},
 		currentIM:     "",
 		config:        cfg,
+		windowIMs:     make(map[string]string),
+	}
+
+	// Rules are compiled by LoadConfig, which rejects invalid rules, but
+	// configs may be built in code
+	if cfg.Rules == nil {
+		if err := config.CompileConfigRules(cfg); err != nil {
+			logger.Errorf("Failed to compile rules: %v", err)
+		}
+	}
+
+	switcher.rules = cfg.Rules
+	if switcher.rules == nil {
+		switcher.rules = &config.RuleSet{}
+	}
+	if cfg.PasswordSafe.Enabled {
+		switcher.passwordRules = cfg.PasswordSafe.Rules
+	}
+	if cfg.GameMode.Enabled {
+		switcher.gameRules = cfg.GameMode.Rules
 	}
 
-	// Initialize input method handlers
-	if cfg.Fcitx5.Enabled {
-		switcher.fcitx5 = NewFcitx5(cfg.Fcitx5.RimeInputMethod)
-		switcher.rime = NewRime(cfg.RimeSchemas)
+	// Initialize input method backend
+	backend, err := NewBackend(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize input method backend: %v", err)
+	} else if backend != nil {
+		logger.Infof("Using input method backend: %s", backend.Name())
+		switcher.backend = backend
+	}
+
+	// Initialize window source
+	source, err := NewWindowSource(cfg)
+	if err != nil {
+		logger.Errorf("Failed to initialize window source: %v", err)
+	} else {
+		logger.Infof("Using window source: %s", source.Name())
+		switcher.source = source
 	}
 
 	return switcher
@@ -57,196 +164,604 @@ func (s *Switcher) SetNotifier(notifier interface {
 	s.notifier = notifier
 }
 
+// SetChangeListener sets a function called after every input method switch.
+// It is called with the switcher locked, so it must not call back into it.
+func (s *Switcher) SetChangeListener(listener func(change Change)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.listener = listener
+}
+
+// SetClassMemory sets the store of learned input methods per window class
+func (s *Switcher) SetClassMemory(store *state.Store) {
+	s.classMemory = store
+}
+
 func (s *Switcher) MonitorAndSwitch(ctx context.Context) error {
-	logger.Debug("Starting Hyprland input method switcher...")
+	if s.source == nil {
+		return fmt.Errorf("no window source available")
+	}
+
+	logger.Debugf("Starting %s input method switcher...", s.source.Name())
 
 	// Process initial window
+	s.mutex.Lock()
 	if err := s.processCurrentWindow(); err != nil {
 		logger.Warningf("Error processing initial window: %v", err)
 	}
+	s.mutex.Unlock()
+
+	// Start window event monitoring
+	events := make(chan WindowEvent, 16)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- s.source.Run(ctx, events)
+	}()
+
+	// Foreground processes change without window events, so poll them
+	var recheck <-chan time.Time
+	if s.rules.UsesForeground && s.config.Process.PollInterval > 0 {
+		ticker := time.NewTicker(time.Duration(s.config.Process.PollInterval) * time.Millisecond)
+		defer ticker.Stop()
+		recheck = ticker.C
+	}
 
-	// Start IPC event monitoring
-	return s.monitorHyprlandEvents(ctx)
+	for {
+		select {
+		case event := <-events:
+			s.mutex.Lock()
+			s.handleWindowEvent(event)
+			s.mutex.Unlock()
+		case <-recheck:
+			s.mutex.Lock()
+			if err := s.processForegroundChange(); err != nil {
+				logger.Warningf("Error processing foreground process change: %v", err)
+			}
+			s.mutex.Unlock()
+		case err := <-errChan:
+			return err
+		}
+	}
 }
 
-func (s *Switcher) monitorHyprlandEvents(ctx context.Context) error {
-	// Get Hyprland IPC socket path
-	socketPath := s.getHyprlandEventSocket()
-	if socketPath == "" {
-		return fmt.Errorf("failed to get Hyprland event socket path")
+// handleWindowEvent dispatches an event from the window source
+func (s *Switcher) handleWindowEvent(event WindowEvent) {
+	if s.paused {
+		// Keep following focus, so resuming applies to the right window
+		switch event.Type {
+		case WindowFocused, WorkspaceFocused:
+			s.currentClient = event.Client
+			return
+		case WindowTitleChanged, WindowStateChanged:
+			if event.Client.Address == s.currentClient.Address {
+				s.currentClient = event.Client
+			}
+			return
+		case LayerOpened, LayerClosed:
+			return
+		}
 	}
 
-	logger.Debugf("Connecting to Hyprland event socket: %s", socketPath)
+	switch event.Type {
+	case WindowFocused:
+		// Check if this is the same window we're already tracking
+		if event.Client.Address == s.currentClient.Address {
+			logger.Tracef("Same window address, skipping: %s", event.Client.Address)
+			return
+		}
 
-	for {
-		select {
-		case <-ctx.Done():
-			logger.Info("Stopping input method switcher...")
-			return nil
-		default:
+		if err := s.processWindowChange(event.Client); err != nil {
+			logger.Warningf("Error processing window change: %v", err)
 		}
 
-		// Connect to socket
-		conn, err := net.Dial("unix", socketPath)
-		if err != nil {
-			logger.Errorf("Failed to connect to Hyprland event socket: %v", err)
-			// Retry after delay
-			select {
-			case <-ctx.Done():
-				return nil
-			case <-time.After(5 * time.Second):
-				continue
-			}
+	case WindowTitleChanged:
+		// Only the focused window's title affects the input method
+		if event.Client.Address != s.currentClient.Address {
+			return
+		}
+
+		if err := s.processTitleChange(event.Client); err != nil {
+			logger.Warningf("Error processing title change: %v", err)
 		}
 
-		logger.Debug("Connected to Hyprland event socket")
+	case WorkspaceFocused:
+		if err := s.processWorkspaceChange(event.Client); err != nil {
+			logger.Warningf("Error processing workspace change: %v", err)
+		}
 
-		// Monitor events
-		err = s.handleEvents(ctx, conn)
-		conn.Close()
+	case WindowStateChanged:
+		if event.Client.Address != s.currentClient.Address {
+			return
+		}
 
-		if err != nil && err != context.Canceled {
-			logger.Errorf("Event monitoring error: %v", err)
-			// Retry after delay
-			select {
-			case <-ctx.Done():
-				return nil
-			case <-time.After(5 * time.Second):
-				continue
-			}
+		if err := s.processStateChange(event.Client); err != nil {
+			logger.Warningf("Error processing window state change: %v", err)
 		}
 
-		if ctx.Err() != nil {
-			return nil
+	case WindowClosed:
+		delete(s.windowIMs, event.Client.Address)
+
+		// Sources report the close before focusing the next window, which
+		// must not remember anything for the closed one
+		if event.Client.Address == s.currentClient.Address {
+			s.currentClient = &ClientInfo{}
+			s.currentRule = nil
+		}
+
+	case LayerOpened:
+		if err := s.processLayerOpen(event.Name); err != nil {
+			logger.Warningf("Error processing layer open: %v", err)
+		}
+
+	case LayerClosed:
+		if err := s.processLayerClose(event.Name); err != nil {
+			logger.Warningf("Error processing layer close: %v", err)
+		}
+
+	case CompositorEvent:
+		// Let the backend track state it can observe from events
+		if handler, ok := s.backend.(EventHandler); ok {
+			handler.HandleEvent(event.Name, event.Data)
 		}
 	}
 }
 
-func (s *Switcher) handleEvents(ctx context.Context, conn net.Conn) error {
-	scanner := bufio.NewScanner(conn)
+func (s *Switcher) processWindowChange(clientInfo *ClientInfo) error {
+	s.resolveProcess(clientInfo)
 
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			return context.Canceled
-		default:
-		}
+	// Credential prompts outrank all client rules
+	if prompt := s.findPasswordRule(clientInfo); prompt != nil {
+		return s.enterCredentialPrompt(prompt, clientInfo)
+	}
+
+	if s.isGame(clientInfo) {
+		return s.enterGameMode(clientInfo)
+	}
 
-		line := scanner.Text()
-		if line == "" {
-			continue
+	rule := s.findMatchingRule(clientInfo)
+	if ruleAction(rule) == config.ActionIgnore {
+		// Act as if the window never gained focus
+		logger.Debugf("Ignoring window: %s - %s (address: %s)", clientInfo.Class, clientInfo.Title, clientInfo.Address)
+		return nil
+	}
+
+	returnsFromPrompt := s.credentialPrompt && clientInfo.Address == s.promptReturnAddress
+
+	s.leaveCurrentWindow()
+
+	// Update current client info
+	s.currentClient = clientInfo
+	s.currentRule = rule
+
+	logger.Debugf("Window changed: %s - %s (address: %s)", clientInfo.Class, clientInfo.Title, clientInfo.Address)
+
+	// Give the window that was interrupted by a credential prompt its input method back
+	if returnsFromPrompt && s.previousIM != "" {
+		logger.Tracef("Restoring input method from before the credential prompt: %s", s.previousIM)
+		return s.applyInputMethod(s.previousIM, clientInfo, ReasonRestore)
+	}
+
+	// A window's own last input method wins over what its rule asks for
+	if ruleAction(rule) == config.ActionSwitch {
+		if targetIM, remembered := s.windowIMs[clientInfo.Address]; remembered {
+			logger.Tracef("Restoring remembered input method for %s: %s", clientInfo.Address, targetIM)
+			return s.applyInputMethod(targetIM, clientInfo, ReasonWindowMemory)
 		}
+	}
+
+	targetIM, reason, switches := s.resolveTarget(rule, clientInfo)
+	if !switches {
+		return nil
+	}
+
+	return s.applyInputMethod(targetIM, clientInfo, reason)
+}
+
+// processWorkspaceChange applies the rule for an empty workspace. Without a
+// workspace or monitor rule the input method is left alone.
+func (s *Switcher) processWorkspaceChange(clientInfo *ClientInfo) error {
+	s.leaveCurrentWindow()
+
+	s.currentClient = clientInfo
+	s.currentRule = s.findMatchingRule(clientInfo)
+
+	logger.Debugf("Workspace changed: %s (monitor: %s)", clientInfo.Workspace.Name, clientInfo.Monitor)
+
+	if s.currentRule == nil {
+		return nil
+	}
+
+	targetIM, reason, switches := s.resolveTarget(s.currentRule, clientInfo)
+	if !switches {
+		return nil
+	}
+
+	return s.applyInputMethod(targetIM, clientInfo, reason)
+}
 
-		// Parse event
-		parts := strings.SplitN(line, ">>", 2)
-		if len(parts) != 2 {
-			continue
+// leaveCurrentWindow records the input method of the window that is about
+// to lose focus. It is what restore_previous rules return to, and with
+// input method memory it is restored when the window is refocused and used
+// for new windows of the same class.
+func (s *Switcher) leaveCurrentWindow() {
+	// Input methods forced on credential prompts and games are not worth remembering
+	if s.credentialPrompt || s.gameMode {
+		s.credentialPrompt = false
+		s.gameMode = false
+		return
+	}
+
+	currentIM := s.GetCurrent()
+	if s.launcher != "" {
+		// Focus moved while a launcher was open, its input method was temporary
+		currentIM = s.launcherReturnIM
+		s.launcher = ""
+	}
+	if currentIM == "unknown" {
+		return
+	}
+
+	s.previousIM = currentIM
+
+	// Closed windows have an empty current client, so nothing is
+	// remembered for them
+	remembersWindow := s.config.Memory.Mode == config.MemoryModeWindow
+	learnsClass := s.config.Memory.Persist && s.classMemory != nil
+	if (!remembersWindow && !learnsClass) || s.currentClient.Address == "" {
+		return
+	}
+
+	if remembersWindow {
+		logger.Tracef("Remembering input method for %s: %s", s.currentClient.Address, currentIM)
+		s.windowIMs[s.currentClient.Address] = currentIM
+	}
+
+	if learnsClass {
+		s.classMemory.Set(s.currentClient.Class, currentIM)
+	}
+}
+
+// processTitleChange re-evaluates rules when the focused window's title
+// changes. Switching only happens if the rule being left or the rule being
+// entered opts in with follow_title, so titles don't fight the user's typing.
+func (s *Switcher) processTitleChange(clientInfo *ClientInfo) error {
+	previousRule := s.currentRule
+
+	// The process is unchanged, the foreground job is polled separately
+	if clientInfo.PID == s.currentClient.PID {
+		clientInfo.Process = s.currentClient.Process
+	}
+
+	// Games don't switch at all while focused
+	if s.gameMode {
+		s.currentClient = clientInfo
+		return nil
+	}
+
+	// Titles asking for a password are credential prompts regardless of follow_title
+	if prompt := s.findPasswordRule(clientInfo); prompt != nil {
+		if s.credentialPrompt {
+			s.currentClient = clientInfo
+			return nil
 		}
+		return s.enterCredentialPrompt(prompt, clientInfo)
+	}
 
-		eventType := parts[0]
-		eventData := parts[1]
+	if s.credentialPrompt {
+		s.credentialPrompt = false
+		s.currentClient = clientInfo
+		s.currentRule = s.findMatchingRule(clientInfo)
 
-		logger.Tracef("Received event: %s >> %s", eventType, eventData)
+		logger.Debugf("Credential prompt left: %s - %s (address: %s)", clientInfo.Class, clientInfo.Title, clientInfo.Address)
 
-		// Handle window focus events - prefer activewindowv2 for better info
-		switch eventType {
-		case "activewindowv2":
-			if err := s.handleActiveWindowV2Event(eventData); err != nil {
-				logger.Warningf("Error handling activewindowv2 event: %v", err)
-			}
-		case "activewindow":
-			// Fallback for older Hyprland versions
-			if err := s.handleActiveWindowEvent(eventData); err != nil {
-				logger.Warningf("Error handling activewindow event: %v", err)
-			}
+		if s.previousIM == "" {
+			return nil
 		}
+		return s.applyInputMethod(s.previousIM, clientInfo, ReasonRestore)
 	}
 
-	return scanner.Err()
+	s.currentClient = clientInfo
+	s.currentRule = s.findMatchingRule(clientInfo)
+
+	if !followsTitle(previousRule) && !followsTitle(s.currentRule) {
+		logger.Tracef("Title changed without follow_title rule: %s", clientInfo.Title)
+		return nil
+	}
+
+	if previousRule == s.currentRule {
+		return nil
+	}
+
+	logger.Debugf("Title changed: %s - %s (address: %s)", clientInfo.Class, clientInfo.Title, clientInfo.Address)
+
+	targetIM, reason, switches := s.resolveTarget(s.currentRule, clientInfo)
+	if !switches {
+		return nil
+	}
+
+	return s.applyInputMethod(targetIM, clientInfo, reason)
 }
 
-func (s *Switcher) handleActiveWindowV2Event(eventData string) error {
-	// eventData format: "windowaddress" (hex address like 0x12345678)
-	windowAddress := strings.TrimSpace(eventData)
-	if windowAddress == "" {
-		logger.Tracef("Empty activewindowv2 event data")
+// processForegroundChange re-evaluates rules for the focused window's
+// process, so starting an editor in a focused terminal can switch. Like
+// title changes, it only switches if the matching rule changed.
+func (s *Switcher) processForegroundChange() error {
+	if s.currentClient.PID <= 0 || s.credentialPrompt || s.gameMode || s.paused {
 		return nil
 	}
 
-	logger.Tracef("Active window changed to address: %s", windowAddress)
+	previousRule := s.currentRule
 
-	// Check if this is the same window we're already tracking
-	if windowAddress == s.currentClient.Address {
-		logger.Tracef("Same window address, skipping: %s", windowAddress)
+	clientInfo := *s.currentClient
+	clientInfo.Process = nil
+	s.resolveProcess(&clientInfo)
+
+	s.currentClient = &clientInfo
+	s.currentRule = s.findMatchingRule(&clientInfo)
+
+	if previousRule == s.currentRule {
 		return nil
 	}
 
-	// Get full client info for the active window
-	clientInfo, err := s.getCurrentClient()
-	if err != nil {
-		return fmt.Errorf("failed to get current client: %w", err)
+	logger.Debugf("Foreground process changed: %s (address: %s)", foregroundCmdline(&clientInfo), clientInfo.Address)
+
+	targetIM, reason, switches := s.resolveTarget(s.currentRule, &clientInfo)
+	if !switches {
+		return nil
+	}
+
+	return s.applyInputMethod(targetIM, &clientInfo, reason)
+}
+
+// enterCredentialPrompt forces plain keyboard input while a credential
+// prompt has focus. The input method from before is restored when focus
+// returns to the window the prompt interrupted.
+func (s *Switcher) enterCredentialPrompt(rule *config.ClientRule, clientInfo *ClientInfo) error {
+	if !s.credentialPrompt {
+		s.leaveCurrentWindow()
+		s.promptReturnAddress = s.currentClient.Address
+		s.credentialPrompt = true
+	}
+
+	s.currentClient = clientInfo
+	s.currentRule = nil
+
+	logger.Debugf("Credential prompt focused: %s - %s (address: %s)", clientInfo.Class, clientInfo.Title, clientInfo.Address)
+
+	targetIM := rule.InputMethod
+	if targetIM == "" {
+		targetIM = s.config.PasswordSafe.InputMethod
+	}
+	if targetIM == "" {
+		targetIM = s.config.DefaultInputMethod
 	}
 
-	// Verify the event matches current window address
-	if clientInfo.Address != windowAddress {
-		logger.Tracef("Event address mismatch: got %s, expected %s", windowAddress, clientInfo.Address)
+	if err := s.applyInputMethod(targetIM, clientInfo, ReasonCredentialPrompt); err != nil {
+		return err
+	}
+
+	if !s.config.PasswordSafe.Deactivate {
 		return nil
 	}
 
-	// Process window change
-	return s.processWindowChange(clientInfo)
+	return s.deactivateBackend()
 }
 
-func (s *Switcher) handleActiveWindowEvent(eventData string) error {
-	// eventData format: "class,title"
-	parts := strings.SplitN(eventData, ",", 2)
-	if len(parts) < 2 {
-		logger.Warningf("Invalid activewindow event data: %s", eventData)
+// processLayerOpen switches temporarily while a launcher matching a layer
+// rule is open. Launchers are layer surfaces, so focus stays on the window.
+func (s *Switcher) processLayerOpen(namespace string) error {
+	if s.credentialPrompt || s.gameMode || s.launcher != "" {
 		return nil
 	}
 
-	class := parts[0]
-	title := parts[1]
+	rule := s.findLayerRule(namespace)
+	if rule == nil {
+		return nil
+	}
 
-	logger.Tracef("Active window changed: class=%s, title=%s", class, title)
+	layerInfo := &ClientInfo{Class: namespace}
+	targetIM, _, switches := s.resolveTarget(rule, layerInfo)
+	if !switches {
+		return nil
+	}
 
-	// Get full client info for the active window
-	clientInfo, err := s.getCurrentClient()
-	if err != nil {
-		return fmt.Errorf("failed to get current client: %w", err)
+	currentIM := s.GetCurrent()
+	if currentIM == "unknown" {
+		return nil
 	}
 
-	// Verify the event matches current window
-	if clientInfo.Class != class {
-		logger.Tracef("Event class mismatch: got %s, expected %s", class, clientInfo.Class)
+	logger.Debugf("Launcher opened: %s", namespace)
+
+	s.launcher = namespace
+	s.launcherReturnIM = currentIM
+
+	return s.applyInputMethod(targetIM, layerInfo, ReasonLauncher)
+}
+
+// processLayerClose restores the input method from before the launcher
+// opened, unless focus moved in the meantime
+func (s *Switcher) processLayerClose(namespace string) error {
+	if s.launcher == "" || namespace != s.launcher {
 		return nil
 	}
 
-	// Check if this is the same window we're already tracking
-	if clientInfo.Address == s.currentClient.Address {
-		logger.Tracef("Same window address, skipping: %s", clientInfo.Address)
+	logger.Debugf("Launcher closed: %s", namespace)
+
+	s.launcher = ""
+	return s.applyInputMethod(s.launcherReturnIM, s.currentClient, ReasonRestore)
+}
+
+// findLayerRule returns the first client rule matching a layer namespace, or nil
+func (s *Switcher) findLayerRule(namespace string) *config.ClientRule {
+	for i := range s.rules.Rules {
+		rule := &s.rules.Rules[i]
+		if rule.Layer != nil && matchField("layer", rule.Layer, namespace) {
+			logger.Tracef("Matched layer rule %d: layer=%s -> %s", rule.Index, rule.Rule.Layer, rule.Rule.InputMethod)
+			return rule.Rule
+		}
+	}
+
+	return nil
+}
+
+// processStateChange enters or leaves game mode when the focused window
+// enters or leaves fullscreen
+func (s *Switcher) processStateChange(clientInfo *ClientInfo) error {
+	if clientInfo.PID == s.currentClient.PID {
+		clientInfo.Process = s.currentClient.Process
+	}
+
+	game := s.isGame(clientInfo)
+
+	switch {
+	case s.credentialPrompt:
+		s.currentClient = clientInfo
 		return nil
+
+	case game && !s.gameMode:
+		return s.enterGameMode(clientInfo)
+
+	case !game && s.gameMode:
+		s.gameMode = false
+		s.currentClient = clientInfo
+		s.currentRule = s.findMatchingRule(clientInfo)
+
+		logger.Debugf("Game mode left: %s - %s (address: %s)", clientInfo.Class, clientInfo.Title, clientInfo.Address)
+
+		if s.previousIM == "" {
+			return nil
+		}
+		return s.applyInputMethod(s.previousIM, clientInfo, ReasonRestore)
 	}
 
-	// Process window change
-	return s.processWindowChange(clientInfo)
+	s.currentClient = clientInfo
+	return nil
 }
 
-func (s *Switcher) processWindowChange(clientInfo *ClientInfo) error {
-	// Update current client info
+// isGame checks if a window is a game: a fullscreen window, if enabled, or
+// one matching a game mode rule
+func (s *Switcher) isGame(clientInfo *ClientInfo) bool {
+	if s.gameRules == nil || clientInfo == nil {
+		return false
+	}
+
+	if s.config.GameMode.Fullscreen && clientInfo.Fullscreen.IsFullscreen() {
+		return true
+	}
+
+	for i := range s.gameRules.Rules {
+		if rule := &s.gameRules.Rules[i]; ruleMatches(rule, clientInfo) {
+			logger.Tracef("Matched game mode %s: class=%s, title=%s", rule.Describe(), rule.Rule.Class, rule.Rule.Title)
+			return true
+		}
+	}
+
+	return false
+}
+
+// enterGameMode suspends switching and notifications while a game has
+// focus. They resume when focus leaves the game or it leaves fullscreen.
+func (s *Switcher) enterGameMode(clientInfo *ClientInfo) error {
+	if !s.gameMode {
+		s.leaveCurrentWindow()
+		s.gameMode = true
+	}
+
 	s.currentClient = clientInfo
+	s.currentRule = nil
+
+	logger.Debugf("Game focused, suspending switching: %s - %s (address: %s)", clientInfo.Class, clientInfo.Title, clientInfo.Address)
 
+	if !s.config.GameMode.Deactivate {
+		return nil
+	}
+
+	return s.deactivateBackend()
+}
+
+// deactivateBackend turns the input method engine off, if the backend can
+func (s *Switcher) deactivateBackend() error {
+	deactivator, ok := s.backend.(Deactivator)
+	if !ok {
+		logger.Debug("Input method backend cannot be deactivated")
+		return nil
+	}
+
+	return deactivator.Deactivate()
+}
+
+// resolveTarget returns the input method the matching rule asks for and the
+// reason for it, and false if the rule leaves the input method alone
+func (s *Switcher) resolveTarget(rule *config.ClientRule, clientInfo *ClientInfo) (string, string, bool) {
+	switch ruleAction(rule) {
+	case config.ActionKeep, config.ActionIgnore:
+		logger.Tracef("Rule keeps the input method for %s", clientInfo.Class)
+		return "", "", false
+
+	case config.ActionRestorePrevious:
+		if s.previousIM == "" {
+			return "", "", false
+		}
+		logger.Tracef("Restoring previous input method for %s: %s", clientInfo.Class, s.previousIM)
+		return s.previousIM, ReasonRestorePrevious, true
+
+	default:
+		targetIM, reason := s.getTargetInputMethod(rule, clientInfo)
+		return targetIM, reason, true
+	}
+}
+
+// ruleAction returns the action of a rule, switch if no rule matched
+func ruleAction(rule *config.ClientRule) string {
+	if rule == nil || rule.Action == "" {
+		return config.ActionSwitch
+	}
+	return rule.Action
+}
+
+// resolveProcess looks up the window's process if rules match on it
+func (s *Switcher) resolveProcess(clientInfo *ClientInfo) {
+	// Process lookups walk /proc, so they only happen if rules need them
+	usesProcess := s.rules.UsesProcess ||
+		(s.passwordRules != nil && s.passwordRules.UsesProcess) ||
+		(s.gameRules != nil && s.gameRules.UsesProcess)
+	if !usesProcess || clientInfo.Process != nil || clientInfo.PID <= 0 {
+		return
+	}
+
+	process, err := LookupProcess(clientInfo.PID)
+	if err != nil {
+		logger.Debugf("Failed to look up process of %s: %v", clientInfo.Address, err)
+		return
+	}
+
+	clientInfo.Process = process
+}
+
+// foregroundCmdline returns the command line of the window's foreground process
+func foregroundCmdline(clientInfo *ClientInfo) string {
+	if clientInfo.Process == nil || clientInfo.Process.Foreground == nil {
+		return ""
+	}
+	return clientInfo.Process.Foreground.Cmdline
+}
+
+// followsTitle checks if a rule opts in to title-driven switching
+func followsTitle(rule *config.ClientRule) bool {
+	return rule != nil && rule.FollowTitle
+}
+
+// applyInputMethod switches to the target input method if it is not active,
+// shows a notification and reports the change to the listener
+func (s *Switcher) applyInputMethod(targetIM string, clientInfo *ClientInfo, reason string) error {
 	// Get current input method status
 	currentIM := s.GetCurrent()
 
-	// Determine target input method
-	targetIM := s.getTargetInputMethod(clientInfo)
-
-	logger.Debugf("Window changed: %s - %s (address: %s)", clientInfo.Class, clientInfo.Title, clientInfo.Address)
 	logger.Debugf("Current IM: %s -> Target IM: %s", currentIM, targetIM)
 
 	// If input method needs to be switched
-	if currentIM != targetIM && currentIM != "unknown" {
+	if currentIM != "unknown" && !s.isActive(targetIM, currentIM) {
 		if err := s.Switch(targetIM); err != nil {
 			return fmt.Errorf("failed to switch input method to %s: %w", targetIM, err)
 		}
@@ -263,241 +778,445 @@ func (s *Switcher) processWindowChange(clientInfo *ClientInfo) error {
 			}
 			s.notifier.ShowInputMethodSwitch(targetIM, windowInfo)
 		}
+
+		s.reportChange(targetIM, clientInfo, reason)
 	}
 
 	return nil
 }
 
+// isActive checks if the target input method is already active, asking
+// the backend when it spells input methods differently than the target
+func (s *Switcher) isActive(targetIM, currentIM string) bool {
+	if currentIM == targetIM {
+		return true
+	}
+
+	if checker, ok := s.backend.(ActiveChecker); ok {
+		return checker.IsActive(targetIM)
+	}
+
+	return false
+}
+
 func (s *Switcher) processCurrentWindow() error {
-	clientInfo, err := s.getCurrentClient()
+	clientInfo, err := s.source.ActiveWindow()
 	if err != nil {
 		return fmt.Errorf("failed to get current client: %w", err)
 	}
 
+	if s.paused {
+		s.currentClient = clientInfo
+		return nil
+	}
+
 	return s.processWindowChange(clientInfo)
 }
 
-func (s *Switcher) getHyprlandEventSocket() string {
-	logger.Tracef("Searching for Hyprland IPC socket...")
+// Pause suspends automatic switching until Resume is called. Focus is still
+// followed, and the input method can still be switched on request.
+func (s *Switcher) Pause() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	// Get XDG_RUNTIME_DIR
-	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
-	if runtimeDir == "" {
-		logger.Tracef("XDG_RUNTIME_DIR not set, falling back to /tmp")
-		runtimeDir = "/tmp"
+	if !s.paused {
+		logger.Info("Automatic switching paused")
 	}
-	logger.Tracef("Using runtime directory: %s", runtimeDir)
+	s.paused = true
+}
 
-	// Try environment variables first
-	if hyprInstance := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE"); hyprInstance != "" {
-		logger.Tracef("Found HYPRLAND_INSTANCE_SIGNATURE: %s", hyprInstance)
-		socketPath := fmt.Sprintf("%s/hypr/%s/.socket2.sock", runtimeDir, hyprInstance)
-		logger.Tracef("Checking socket path: %s", socketPath)
-		if _, err := os.Stat(socketPath); err == nil {
-			logger.Debug("Found Hyprland IPC socket via environment")
-			return socketPath
-		} else {
-			logger.Tracef("Hyprland IPC Socket not found via environment: %v", err)
-		}
-	} else {
-		logger.Tracef("HYPRLAND_INSTANCE_SIGNATURE not set")
+// Resume resumes automatic switching and applies the rules to the focused window
+func (s *Switcher) Resume() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.paused {
+		return nil
 	}
 
-	// Check if hypr directory exists in runtime dir
-	hyprDir := fmt.Sprintf("%s/hypr", runtimeDir)
-	if _, err := os.Stat(hyprDir); os.IsNotExist(err) {
-		logger.Errorf("Hyprland directory %s does not exist. Is Hyprland running?", hyprDir)
-		return ""
+	logger.Info("Automatic switching resumed")
+	s.paused = false
+
+	// Treat the focused window as newly focused
+	clientInfo := s.currentClient
+	s.currentClient = &ClientInfo{}
+	s.currentRule = nil
+
+	if clientInfo.Address == "" {
+		return s.processWorkspaceChange(clientInfo)
 	}
+	return s.processWindowChange(clientInfo)
+}
 
-	// List all directories in runtime/hypr/
-	entries, err := os.ReadDir(hyprDir)
-	if err != nil {
-		logger.Errorf("Failed to read Hyprland directory %s: %v", hyprDir, err)
-		return ""
+// Paused checks if automatic switching is paused
+func (s *Switcher) Paused() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.paused
+}
+
+// WindowMemory returns the remembered input method per window, including
+// the focused window's, so a switcher for a reloaded configuration keeps them
+func (s *Switcher) WindowMemory() map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	memory := make(map[string]string, len(s.windowIMs)+1)
+	for address, inputMethod := range s.windowIMs {
+		memory[address] = inputMethod
 	}
 
-	logger.Tracef("Found %d entries in %s", len(entries), hyprDir)
-	for _, entry := range entries {
-		if entry.IsDir() {
-			socketPath := fmt.Sprintf("%s/%s/.socket2.sock", hyprDir, entry.Name())
-			logger.Tracef("Checking socket: %s", socketPath)
-			if _, err := os.Stat(socketPath); err == nil {
-				logger.Debug("Found Hyprland event socket")
-				return socketPath
-			} else {
-				logger.Tracef("Socket not found: %v", err)
-			}
+	// The focused window is only remembered when it loses focus, which the
+	// old switcher won't see anymore
+	forced := s.credentialPrompt || s.gameMode || s.launcher != ""
+	if s.config.Memory.Mode == config.MemoryModeWindow && s.currentClient.Address != "" && !forced {
+		if currentIM := s.GetCurrent(); currentIM != "unknown" {
+			memory[s.currentClient.Address] = currentIM
 		}
 	}
 
-	// Fallback: try to find socket using glob pattern in runtime dir
-	globPattern := fmt.Sprintf("%s/hypr/*/.socket2.sock", runtimeDir)
-	logger.Tracef("Trying glob pattern: %s", globPattern)
-	matches, err := filepath.Glob(globPattern)
-	if err != nil {
-		logger.Errorf("Glob pattern failed: %v", err)
-		return ""
-	}
+	return memory
+}
 
-	logger.Tracef("Glob found %d matches", len(matches))
-	for _, match := range matches {
-		logger.Tracef("Glob match: %s", match)
+// SetWindowMemory replaces the remembered input methods per window
+func (s *Switcher) SetWindowMemory(memory map[string]string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.windowIMs = make(map[string]string, len(memory))
+	for address, inputMethod := range memory {
+		s.windowIMs[address] = inputMethod
 	}
+}
 
-	if len(matches) == 0 {
-		logger.Error("No Hyprland event sockets found. Please check:")
-		logger.Error("1. Is Hyprland running?")
-		logger.Error("2. Are you running this inside a Hyprland session?")
-		logger.Errorf("3. Check if %s/hypr directory exists and contains instance directories", runtimeDir)
+// SwitchInputMethod switches to an input method on request. With input
+// method memory, the focused window keeps it like a manual switch.
+func (s *Switcher) SwitchInputMethod(inputMethod string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-		// List what's actually in runtime/hypr if it exists
-		if entries, err := os.ReadDir(hyprDir); err == nil {
-			logger.Errorf("Contents of %s:", hyprDir)
-			for _, entry := range entries {
-				logger.Errorf("  - %s (dir: %v)", entry.Name(), entry.IsDir())
-			}
-		}
+	if err := s.Switch(inputMethod); err != nil {
+		return err
+	}
 
-		// Also check for legacy /tmp/hypr path
-		logger.Trace("Checking legacy /tmp/hypr path...")
-		legacyPattern := "/tmp/hypr/*/.socket2.sock"
-		if legacyMatches, err := filepath.Glob(legacyPattern); err == nil && len(legacyMatches) > 0 {
-			logger.Debug("Found legacy socket")
-			return legacyMatches[0]
-		}
+	logger.Infof("Switched input method to %s on request", inputMethod)
+	s.currentIM = inputMethod
+	s.reportChange(inputMethod, s.currentClient, ReasonRequest)
+	return nil
+}
 
-		return ""
+// reportChange tells the listener about an input method switch
+func (s *Switcher) reportChange(inputMethod string, clientInfo *ClientInfo, reason string) {
+	if s.listener == nil {
+		return
 	}
 
-	// Use the first available socket
-	logger.Debug("Using first available socket")
-	return matches[0]
+	s.listener(Change{
+		InputMethod: inputMethod,
+		Class:       clientInfo.Class,
+		Title:       clientInfo.Title,
+		Reason:      reason,
+	})
 }
 
-func (s *Switcher) getCurrentClient() (*ClientInfo, error) {
-	cmd := exec.Command("hyprctl", "activewindow", "-j")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("hyprctl command failed: %w", err)
-	}
+// CurrentInputMethod returns the active input method as reported by the
+// backend, or as last switched to if the backend cannot tell
+func (s *Switcher) CurrentInputMethod() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	var clientInfo ClientInfo
-	if err := json.Unmarshal(output, &clientInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse hyprctl output: %w", err)
+	currentIM := s.GetCurrent()
+	if currentIM == "unknown" && s.currentIM != "" {
+		return s.currentIM
 	}
+	return currentIM
+}
 
-	return &clientInfo, nil
+// RuleMatch returns the focused window and the rule matching it
+func (s *Switcher) RuleMatch() map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return map[string]interface{}{
+		"client":            s.currentClient,
+		"rule":              s.explainRule(),
+		"credential_prompt": s.credentialPrompt,
+		"game_mode":         s.gameMode,
+		"launcher":          s.launcher,
+	}
 }
 
 func (s *Switcher) GetCurrent() string {
-	if !s.config.Fcitx5.Enabled || s.fcitx5 == nil {
+	if s.backend == nil {
 		return "unknown"
 	}
 
-	// Get current fcitx5 input method
-	currentIM := s.fcitx5.GetCurrent()
+	return s.backend.GetCurrent()
+}
 
-	// If it's Rime, get the specific input method based on schema
-	if currentIM == "rime" && s.rime != nil {
-		return s.rime.GetCurrentInputMethod(s.config.DefaultInputMethod)
+// getTargetInputMethod returns the input method for a window given its
+// matching rule, which may be nil, and the reason for it
+func (s *Switcher) getTargetInputMethod(rule *config.ClientRule, clientInfo *ClientInfo) (string, string) {
+	learnedIM, learned := s.learnedInputMethod(clientInfo)
+	if learned && s.config.Memory.Precedence == config.PrecedenceLearned {
+		logger.Tracef("Using learned input method for %s: %s", clientInfo.Class, learnedIM)
+		return learnedIM, ReasonLearned
 	}
 
-	return currentIM
+	if rule != nil {
+		return rule.InputMethod, ReasonRule
+	}
+
+	if learned {
+		logger.Tracef("Using learned input method for %s: %s", clientInfo.Class, learnedIM)
+		return learnedIM, ReasonLearned
+	}
+
+	logger.Tracef("No matching rule found, using default: %s", s.config.DefaultInputMethod)
+	return s.config.DefaultInputMethod, ReasonDefault
 }
 
-func (s *Switcher) getTargetInputMethod(clientInfo *ClientInfo) string {
-	if clientInfo == nil {
-		return s.config.DefaultInputMethod
+// learnedInputMethod returns the input method last used by the window's class
+func (s *Switcher) learnedInputMethod(clientInfo *ClientInfo) (string, bool) {
+	if !s.config.Memory.Persist || s.classMemory == nil || clientInfo == nil || clientInfo.Class == "" {
+		return "", false
 	}
 
-	className := clientInfo.Class
-	title := clientInfo.Title
+	return s.classMemory.Get(clientInfo.Class)
+}
 
-	logger.Tracef("Matching rules for class: %s, title: %s", className, title)
+// findMatchingRule returns the winning client rule for the window, or nil.
+// Rules are kept in evaluation order, so that is the first matching one.
+func (s *Switcher) findMatchingRule(clientInfo *ClientInfo) *config.ClientRule {
+	if clientInfo == nil {
+		return nil
+	}
+
+	logger.Tracef("Matching rules for class: %s, title: %s, workspace: %s, monitor: %s",
+		clientInfo.Class, clientInfo.Title, clientInfo.Workspace.Name, clientInfo.Monitor)
 
 	// Check client rules
-	for _, rule := range s.config.ClientRules {
-		// Match class (required)
-		if rule.Class == "" || !s.matchPattern(rule.Class, className) {
-			continue
+	for i := range s.rules.Rules {
+		rule := &s.rules.Rules[i]
+
+		if ruleMatches(rule, clientInfo) {
+			logger.Tracef("Matched rule %d: class=%s, title=%s, workspace=%s, monitor=%s -> %s",
+				rule.Index, rule.Rule.Class, rule.Rule.Title, rule.Rule.Workspace, rule.Rule.Monitor, rule.Rule.InputMethod)
+			return rule.Rule
 		}
+	}
 
-		// If title is empty or not specified, class match is enough
-		if rule.Title == "" {
-			logger.Tracef("Matched rule: class=%s -> %s", rule.Class, rule.InputMethod)
-			return rule.InputMethod
+	return nil
+}
+
+// findPasswordRule returns the password-safe rule matching the window, or
+// nil if it is not a credential prompt
+func (s *Switcher) findPasswordRule(clientInfo *ClientInfo) *config.ClientRule {
+	if s.passwordRules == nil || clientInfo == nil {
+		return nil
+	}
+
+	for i := range s.passwordRules.Rules {
+		rule := &s.passwordRules.Rules[i]
+		if ruleMatches(rule, clientInfo) {
+			logger.Tracef("Matched password-safe %s: class=%s, title=%s", rule.Describe(), rule.Rule.Class, rule.Rule.Title)
+			return rule.Rule
 		}
+	}
+
+	return nil
+}
 
-		// If title is specified, both class and title must match
-		if s.matchPattern(rule.Title, title) {
-			logger.Tracef("Matched rule: class=%s, title=%s -> %s", rule.Class, rule.Title, rule.InputMethod)
-			return rule.InputMethod
+// explainRule reports which rule won for the current window and why
+func (s *Switcher) explainRule() map[string]interface{} {
+	explanation := map[string]interface{}{
+		"evaluation": s.rules.Evaluation,
+	}
+
+	var winner, runnerUp *config.CompiledRule
+	if s.currentClient != nil {
+		for i := range s.rules.Rules {
+			rule := &s.rules.Rules[i]
+			if !ruleMatches(rule, s.currentClient) {
+				continue
+			}
+			if winner == nil {
+				winner = rule
+			} else {
+				runnerUp = rule
+				break
+			}
 		}
 	}
 
-	logger.Tracef("No matching rule found, using default: %s", s.config.DefaultInputMethod)
-	return s.config.DefaultInputMethod
+	if winner == nil {
+		explanation["reason"] = "no rule matched"
+		return explanation
+	}
+
+	explanation["index"] = winner.Index
+	explanation["priority"] = winner.Rule.Priority
+	explanation["specificity"] = winner.Specificity
+	explanation["conditions"] = winner.Conditions
+	explanation["action"] = ruleAction(winner.Rule)
+	explanation["input_method"] = winner.Rule.InputMethod
+	explanation["reason"] = winner.Explain(runnerUp, s.rules.Evaluation)
+
+	return explanation
 }
 
-func (s *Switcher) matchPattern(pattern, text string) bool {
-	if pattern == "" || text == "" {
+// ruleMatches checks if every condition set on a rule holds for the window
+func ruleMatches(rule *config.CompiledRule, clientInfo *ClientInfo) bool {
+	// Layer rules only match launchers, see findLayerRule
+	if !rule.HasSubject() || rule.Layer != nil {
 		return false
 	}
 
-	// Try as regex first
-	if matched, err := regexp.MatchString(pattern, text); err == nil {
-		logger.Tracef("Regex match '%s' against '%s': %v", pattern, text, matched)
-		return matched
+	if rule.Class != nil && !matchField("class", rule.Class, clientInfo.Class) {
+		return false
 	}
 
-	// Fallback to case-insensitive string contains matching
-	matched := strings.Contains(strings.ToLower(text), strings.ToLower(pattern))
-	logger.Tracef("String contains match '%s' against '%s': %v", pattern, text, matched)
-	return matched
+	// If title is specified, it must match as well
+	if rule.Title != nil && !matchField("title", rule.Title, clientInfo.Title) {
+		return false
+	}
+
+	// Negated fields exclude matching windows
+	if rule.NotClass != nil && matchField("not_class", rule.NotClass, clientInfo.Class) {
+		return false
+	}
+
+	if rule.NotTitle != nil && matchField("not_title", rule.NotTitle, clientInfo.Title) {
+		return false
+	}
+
+	if rule.InitialClass != nil && !matchField("initial_class", rule.InitialClass, clientInfo.InitialClass) {
+		return false
+	}
+
+	if rule.InitialTitle != nil && !matchField("initial_title", rule.InitialTitle, clientInfo.InitialTitle) {
+		return false
+	}
+
+	if !matchProcess(rule, clientInfo.Process) {
+		return false
+	}
+
+	if !matchFlag(rule.Rule.XWayland, clientInfo.XWayland) ||
+		!matchFlag(rule.Rule.Floating, clientInfo.Floating) ||
+		!matchFlag(rule.Rule.Fullscreen, clientInfo.Fullscreen.IsFullscreen()) ||
+		!matchFlag(rule.Rule.Pinned, clientInfo.Pinned) ||
+		!matchFlag(rule.Rule.Grouped, len(clientInfo.Grouped) > 0) {
+		return false
+	}
+
+	if rule.Rule.Workspace != "" && !matchWorkspace(rule, clientInfo.Workspace) {
+		return false
+	}
+
+	if rule.Monitor != nil && !matchField("monitor", rule.Monitor, clientInfo.Monitor) {
+		return false
+	}
+
+	return true
 }
 
-func (s *Switcher) Switch(targetMethod string) error {
-	if !s.config.Fcitx5.Enabled || s.fcitx5 == nil {
-		return fmt.Errorf("fcitx5 is not enabled")
+// matchProcess checks the process conditions of a rule
+func matchProcess(rule *config.CompiledRule, process *ProcessInfo) bool {
+	if rule.AppID == nil && rule.Exe == nil && rule.Cmdline == nil && rule.ForegroundExe == nil && rule.ForegroundCmdline == nil {
+		return true
 	}
 
-	logger.Debugf("Switching to input method: %s", targetMethod)
+	if process == nil {
+		return false
+	}
 
-	if targetMethod == "english" {
-		return s.fcitx5.SwitchToEnglish()
+	if rule.AppID != nil && !matchField("app_id", rule.AppID, process.AppID) {
+		return false
 	}
 
-	// For non-English methods, switch to Rime first, then set schema
-	if err := s.fcitx5.SwitchToRime(); err != nil {
-		return fmt.Errorf("failed to switch to Rime: %w", err)
+	if rule.Exe != nil && !matchField("exe", rule.Exe, process.Exe) {
+		return false
 	}
 
-	// Wait a bit for the switch to take effect
-	time.Sleep(100 * time.Millisecond)
+	if rule.Cmdline != nil && !matchField("cmdline", rule.Cmdline, process.Cmdline) {
+		return false
+	}
 
-	// Switch to specific schema if Rime is available
-	if s.rime != nil {
-		return s.rime.SwitchSchema(targetMethod)
+	if rule.ForegroundExe == nil && rule.ForegroundCmdline == nil {
+		return true
 	}
 
-	return nil
+	foreground := process.Foreground
+	if foreground == nil {
+		return false
+	}
+
+	if rule.ForegroundExe != nil && !matchField("foreground_exe", rule.ForegroundExe, foreground.Exe) {
+		return false
+	}
+
+	if rule.ForegroundCmdline != nil && !matchField("foreground_cmdline", rule.ForegroundCmdline, foreground.Cmdline) {
+		return false
+	}
+
+	return true
+}
+
+// matchFlag checks an optional boolean rule condition
+func matchFlag(want *bool, value bool) bool {
+	return want == nil || *want == value
+}
+
+// matchWorkspace matches a workspace ID, "special" for any special
+// workspace, or a workspace name pattern
+func matchWorkspace(rule *config.CompiledRule, workspace WorkspaceInfo) bool {
+	if id, err := strconv.Atoi(rule.Rule.Workspace); err == nil {
+		return workspace.ID != 0 && workspace.ID == id
+	}
+
+	if rule.Rule.Workspace == "special" {
+		return strings.HasPrefix(workspace.Name, "special")
+	}
+
+	return matchField("workspace", rule.Workspace, workspace.Name)
+}
+
+// matchField matches a value against a compiled rule pattern
+func matchField(field string, pattern *config.Pattern, text string) bool {
+	matched := pattern.Match(text)
+	logger.Tracef("Match %s '%s' (%s) against '%s': %v", field, pattern, pattern.Mode(), text, matched)
+	return matched
+}
+
+func (s *Switcher) Switch(targetMethod string) error {
+	if s.backend == nil {
+		return fmt.Errorf("no input method backend available")
+	}
+
+	logger.Debugf("Switching to input method: %s (backend: %s)", targetMethod, s.backend.Name())
+
+	return s.backend.Switch(targetMethod)
 }
 
 // IsReady checks if the switcher is ready to operate
 func (s *Switcher) IsReady() bool {
-	// Check if hyprctl is available
-	if _, err := exec.LookPath("hyprctl"); err != nil {
-		logger.Error("hyprctl not found in PATH")
+	// Check if the compositor is available
+	if s.source == nil || !s.source.IsAvailable() {
+		logger.Error("window source not available")
 		return false
 	}
 
-	// Check if fcitx5 is available and enabled
-	if s.config.Fcitx5.Enabled {
-		if s.fcitx5 == nil || !s.fcitx5.IsAvailable() {
-			logger.Error("fcitx5 is enabled but not available")
-			return false
-		}
+	// Check if the input method backend is available
+	if s.backend == nil {
+		logger.Error("no input method backend available")
+		return false
+	}
+
+	if !s.backend.IsAvailable() {
+		logger.Errorf("input method backend %s is not available", s.backend.Name())
+		return false
 	}
 
 	return true
@@ -505,20 +1224,45 @@ func (s *Switcher) IsReady() bool {
 
 // GetStatus returns current status information
 func (s *Switcher) GetStatus() map[string]interface{} {
+	// Availability probes talk to the compositor and the engine, so run them
+	// before locking. The source and backend never change after creation.
 	status := map[string]interface{}{
-		"current_client": s.currentClient, // Now contains the window address
-		"current_im":     s.currentIM,
-		"fcitx5_enabled": s.config.Fcitx5.Enabled,
-		"ready":          s.IsReady(),
+		"backend":    "none",
+		"compositor": "none",
+	}
+
+	sourceAvailable := false
+	if s.source != nil {
+		status["compositor"] = s.source.Name()
+		sourceAvailable = s.source.IsAvailable()
 	}
 
-	if s.fcitx5 != nil {
-		status["fcitx5_available"] = s.fcitx5.IsAvailable()
+	backendAvailable := false
+	if s.backend != nil {
+		backendAvailable = s.backend.IsAvailable()
+		status["backend"] = s.backend.Name()
+		status["backend_available"] = backendAvailable
+		status["input_methods"] = s.backend.ListInputMethods()
 	}
 
-	if s.rime != nil {
-		status["rime_available"] = s.rime.IsAvailable()
+	if reporter, ok := s.backend.(StatusReporter); ok {
+		for key, value := range reporter.Status() {
+			status[key] = value
+		}
 	}
 
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status["current_client"] = s.currentClient // Now contains the window address
+	status["current_im"] = s.currentIM
+	status["memory_mode"] = s.config.Memory.Mode
+	status["ready"] = sourceAvailable && backendAvailable
+	status["rule"] = s.explainRule()
+	status["credential_prompt"] = s.credentialPrompt
+	status["game_mode"] = s.gameMode
+	status["launcher"] = s.launcher
+	status["paused"] = s.paused
+
 	return status
 }
@@ -0,0 +1,247 @@
+package inputmethod
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"hypr-input-switcher/internal/config"
+)
+
+// benchmarkRules generates a rule set whose only matching rule is the last one
+func benchmarkRules(b *testing.B, count int, mode string) *config.RuleSet {
+	rules := make([]config.ClientRule, count)
+	for i := range rules {
+		rules[i] = config.ClientRule{
+			Class:       fmt.Sprintf("^org\\.example\\.app%04d$", i),
+			Title:       fmt.Sprintf("document %04d", i),
+			Match:       mode,
+			InputMethod: "english",
+		}
+	}
+	if mode == config.MatchExact || mode == config.MatchContains {
+		for i := range rules {
+			rules[i].Class = fmt.Sprintf("org.example.app%04d", i)
+		}
+	}
+	if mode == config.MatchGlob {
+		for i := range rules {
+			rules[i].Class = fmt.Sprintf("org.example.app%04d*", i)
+		}
+	}
+
+	ruleSet, err := config.CompileRules(rules, config.EvaluationFirstMatch)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return ruleSet
+}
+
+func BenchmarkFindMatchingRule(b *testing.B) {
+	for _, count := range []int{10, 100, 500} {
+		for _, mode := range []string{config.MatchAuto, config.MatchExact, config.MatchGlob, config.MatchContains} {
+			b.Run(fmt.Sprintf("%s/%d", mode, count), func(b *testing.B) {
+				switcher := &Switcher{
+					config: &config.Config{},
+					rules:  benchmarkRules(b, count, mode),
+				}
+				clientInfo := &ClientInfo{
+					Class: fmt.Sprintf("org.example.app%04d", count-1),
+					Title: fmt.Sprintf("document %04d", count-1),
+				}
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if switcher.findMatchingRule(clientInfo) == nil {
+						b.Fatal("no rule matched")
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkCompileRules(b *testing.B) {
+	rules := make([]config.ClientRule, 500)
+	for i := range rules {
+		rules[i] = config.ClientRule{Class: fmt.Sprintf("^org\\.example\\.app%04d$", i), Title: "(?i)document"}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := config.CompileRules(rules, config.EvaluationFirstMatch); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// probingBackend is a backend whose availability probe checks that the
+// switcher is not locked while it runs
+type probingBackend struct {
+	switcher *Switcher
+	probes   int
+	locked   bool
+}
+
+func (b *probingBackend) Name() string                    { return "probing" }
+func (b *probingBackend) Detect() bool                    { return true }
+func (b *probingBackend) GetCurrent() string              { return "english" }
+func (b *probingBackend) Switch(inputMethod string) error { return nil }
+func (b *probingBackend) ListInputMethods() []string      { return []string{"english"} }
+func (b *probingBackend) IsAvailable() bool               { return b.probe() }
+func (b *probingBackend) probe() bool {
+	b.probes++
+	if b.switcher.mutex.TryLock() {
+		b.switcher.mutex.Unlock()
+	} else {
+		b.locked = true
+	}
+	return true
+}
+
+// probingSource is a window source that probes like probingBackend
+type probingSource struct {
+	*probingBackend
+}
+
+func (w probingSource) Name() string                       { return "probing" }
+func (w probingSource) IsAvailable() bool                  { return w.probe() }
+func (w probingSource) ActiveWindow() (*ClientInfo, error) { return &ClientInfo{}, nil }
+func (w probingSource) Run(ctx context.Context, events chan<- WindowEvent) error {
+	<-ctx.Done()
+	return nil
+}
+
+func TestGetStatusProbesUnlocked(t *testing.T) {
+	s := &Switcher{config: &config.Config{}, rules: &config.RuleSet{}, currentClient: &ClientInfo{}}
+	backend := &probingBackend{switcher: s}
+	s.backend = backend
+	s.source = probingSource{backend}
+
+	status := s.GetStatus()
+
+	if backend.locked {
+		t.Error("availability was probed while holding the switcher lock")
+	}
+	if backend.probes != 2 {
+		t.Errorf("got %d availability probes, want one each for source and backend", backend.probes)
+	}
+	if status["ready"] != true || status["backend_available"] != true {
+		t.Errorf("unexpected status: %v", status)
+	}
+}
+
+// recordingBackend reports a fixed input method and records switches
+type recordingBackend struct {
+	current  string
+	switches []string
+}
+
+func (b *recordingBackend) Name() string               { return "recording" }
+func (b *recordingBackend) Detect() bool               { return true }
+func (b *recordingBackend) IsAvailable() bool          { return true }
+func (b *recordingBackend) GetCurrent() string         { return b.current }
+func (b *recordingBackend) ListInputMethods() []string { return []string{"english", "chinese"} }
+func (b *recordingBackend) Switch(inputMethod string) error {
+	b.switches = append(b.switches, inputMethod)
+	b.current = inputMethod
+	return nil
+}
+
+// newTestSwitcher creates a switcher for the configuration without
+// detecting a compositor or input method backend
+func newTestSwitcher(t *testing.T, cfg *config.Config, backend Backend) *Switcher {
+	t.Helper()
+
+	rules, err := config.CompileRules(cfg.ClientRules, cfg.RuleEvaluation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Switcher{
+		config:        cfg,
+		rules:         rules,
+		backend:       backend,
+		currentClient: &ClientInfo{},
+		windowIMs:     make(map[string]string),
+	}
+}
+
+func TestWindowMemorySurvivesReload(t *testing.T) {
+	cfg := &config.Config{
+		ClientRules: []config.ClientRule{{Class: "^kitty$", InputMethod: "chinese"}},
+	}
+	cfg.Memory.Mode = config.MemoryModeWindow
+
+	backend := &recordingBackend{current: "english"}
+	old := newTestSwitcher(t, cfg, backend)
+
+	// Both windows get the rule's input method, then the user switches back
+	for _, address := range []string{"0x1", "0x2"} {
+		if err := old.processWindowChange(&ClientInfo{Address: address, Class: "kitty"}); err != nil {
+			t.Fatal(err)
+		}
+		backend.current = "english"
+	}
+
+	// Reloading the configuration creates a new switcher
+	reloaded := newTestSwitcher(t, cfg, backend)
+	reloaded.SetWindowMemory(old.WindowMemory())
+	backend.switches = nil
+
+	for _, address := range []string{"0x1", "0x2"} {
+		backend.current = "chinese"
+		if err := reloaded.processWindowChange(&ClientInfo{Address: address, Class: "kitty"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The window left before the reload and the focused one keep english
+	if fmt.Sprint(backend.switches) != "[english english]" {
+		t.Errorf("switches after reload = %v, want the remembered input methods", backend.switches)
+	}
+}
+
+// reportingBackend adds engine specific status to recordingBackend
+type reportingBackend struct {
+	recordingBackend
+}
+
+func (b *reportingBackend) Status() map[string]interface{} {
+	return map[string]interface{}{"engine_address": "unix:path=/tmp/engine"}
+}
+
+func TestGetStatusBackendStatus(t *testing.T) {
+	s := newTestSwitcher(t, &config.Config{}, &reportingBackend{})
+
+	status := s.GetStatus()
+	if status["engine_address"] != "unix:path=/tmp/engine" || status["backend"] != "recording" {
+		t.Errorf("backend status was not merged: %v", status)
+	}
+	if _, exists := status["fcitx5_enabled"]; exists {
+		t.Error("status reports fcitx5_enabled for another backend")
+	}
+}
+
+func TestFullscreenRuleIgnoresMaximized(t *testing.T) {
+	fullscreen := true
+	rules, err := config.CompileRules([]config.ClientRule{{Class: "^steam_app_", Fullscreen: &fullscreen}}, config.EvaluationFirstMatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		mode    FullscreenMode
+		matches bool
+	}{
+		{FullscreenNone, false},
+		{FullscreenMaximized, false},
+		{FullscreenFull, true},
+		{FullscreenMaximized | FullscreenFull, true},
+	} {
+		client := &ClientInfo{Class: "steam_app_570", Fullscreen: test.mode}
+		if matches := ruleMatches(&rules.Rules[0], client); matches != test.matches {
+			t.Errorf("fullscreen mode %d: matches = %v, want %v", test.mode, matches, test.matches)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package inputmethod
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"hypr-input-switcher/internal/config"
+)
+
+// WindowEventType identifies the kind of a WindowEvent
+type WindowEventType int
+
+const (
+	// WindowFocused is emitted when a window gains focus
+	WindowFocused WindowEventType = iota
+
+	// WindowTitleChanged is emitted when the focused window's title changes
+	WindowTitleChanged
+
+	// WorkspaceFocused is emitted when focus moves onto an empty workspace.
+	// Only the client workspace and monitor are set.
+	WorkspaceFocused
+
+	// WindowClosed is emitted when a window is closed. Only the client
+	// address is guaranteed to be set.
+	WindowClosed
+
+	// WindowStateChanged is emitted when the focused window's state, such
+	// as fullscreen, changes
+	WindowStateChanged
+
+	// LayerOpened and LayerClosed are emitted when a layer surface, such as
+	// a launcher, is mapped or unmapped. Only Name, the layer namespace, is set.
+	LayerOpened
+	LayerClosed
+
+	// CompositorEvent carries a raw compositor event, such as a Hyprland
+	// socket2 line, for backends that track state from events
+	CompositorEvent
+)
+
+// WindowEvent is emitted by a WindowSource
+type WindowEvent struct {
+	Type   WindowEventType
+	Client *ClientInfo // window the event refers to, if any
+	Name   string      // raw event name for CompositorEvent
+	Data   string      // raw event data for CompositorEvent
+}
+
+// WindowSource tracks window focus in a compositor
+type WindowSource interface {
+	// Name returns the compositor name
+	Name() string
+
+	// IsAvailable checks if the compositor can be reached
+	IsAvailable() bool
+
+	// ActiveWindow returns the currently focused window
+	ActiveWindow() (*ClientInfo, error)
+
+	// Run emits window events until the context is cancelled,
+	// reconnecting to the compositor as needed
+	Run(ctx context.Context, events chan<- WindowEvent) error
+}
+
+// NewWindowSource creates the window source selected by the configuration.
+// An empty or "auto" selection is derived from the session environment.
+func NewWindowSource(cfg *config.Config) (WindowSource, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.Compositor))
+	if name == "" || name == "auto" {
+		name = detectCompositor()
+	}
+
+	switch name {
+	case "hyprland":
+		return NewHyprlandSource(), nil
+	case "sway", "i3":
+		return NewSwaySource(), nil
+	case "niri":
+		return NewNiriSource(), nil
+	default:
+		return nil, fmt.Errorf("unknown compositor: %s (available: hyprland, sway, niri)", name)
+	}
+}
+
+// detectCompositor guesses the running compositor from its environment variables
+func detectCompositor() string {
+	switch {
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		return "hyprland"
+	case os.Getenv("SWAYSOCK") != "", os.Getenv("I3SOCK") != "":
+		return "sway"
+	case os.Getenv("NIRI_SOCKET") != "":
+		return "niri"
+	default:
+		return "hyprland"
+	}
+}
+
+// sendEvent delivers an event unless the context is cancelled first
+func sendEvent(ctx context.Context, events chan<- WindowEvent, event WindowEvent) error {
+	select {
+	case events <- event:
+		return nil
+	case <-ctx.Done():
+		return context.Canceled
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"unicode"
 
 	"hypr-input-switcher/internal/config"
+	"hypr-input-switcher/internal/hyprland"
 	"hypr-input-switcher/pkg/logger"
 
 	"github.com/gen2brain/beeep"
@@ -16,6 +17,7 @@ import (
 
 type Notifier struct {
 	config            *config.Config
+	hyprland          *hyprland.Client
 	availableMethods  []string
 	selectedMethod    string
 	iconPath          string
@@ -24,7 +26,8 @@ type Notifier struct {
 
 func NewNotifier(config *config.Config) *Notifier {
 	notifier := &Notifier{
-		config: config,
+		config:   config,
+		hyprland: hyprland.NewClient(),
 	}
 
 	// Setup icon path
@@ -195,7 +198,7 @@ func (n *Notifier) isMethodAvailable(method string) bool {
 	case "dunstify":
 		return n.commandExists("dunstify")
 	case "hyprctl":
-		return n.commandExists("hyprctl") && n.isHyprlandRunning()
+		return n.isHyprlandRunning()
 	case "swaync-client":
 		return n.commandExists("swaync-client")
 	case "mako":
@@ -213,8 +216,7 @@ func (n *Notifier) commandExists(command string) bool {
 
 // isHyprlandRunning checks if Hyprland is running
 func (n *Notifier) isHyprlandRunning() bool {
-	cmd := exec.Command("hyprctl", "version")
-	return cmd.Run() == nil
+	return n.hyprland.IsAvailable()
 }
 
 // isMakoRunning checks if mako is running
@@ -264,7 +266,7 @@ func (n *Notifier) send(method, title, message, icon string) bool {
 		} else {
 			notificationText = fmt.Sprintf("%s: %s", title, message)
 		}
-		cmd = exec.Command("hyprctl", "notify", "2", duration, "0", notificationText)
+		return n.sendHyprland(notificationText)
 
 	case "swaync-client":
 		var fullMessage string
@@ -300,6 +302,17 @@ func (n *Notifier) send(method, title, message, icon string) bool {
 	return true
 }
 
+// sendHyprland sends a Hyprland native notification over its request socket
+func (n *Notifier) sendHyprland(text string) bool {
+	if err := n.hyprland.Notify(2, n.config.Notifications.Duration, "0", text); err != nil {
+		logger.Debugf("Notification method hyprctl failed: %v", err)
+		return false
+	}
+
+	logger.Debugf("Notification sent via hyprctl: %s", text)
+	return true
+}
+
 // isImageFile checks if the path is an image file
 func (n *Notifier) isImageFile(path string) bool {
 	if path == "" {
@@ -0,0 +1,217 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"hypr-input-switcher/pkg/logger"
+)
+
+// stateVersion is the version of the state file format
+const stateVersion = 1
+
+// saveDelay batches changes made in quick succession into a single write
+const saveDelay = 2 * time.Second
+
+// stateFile is the on-disk representation of the store
+type stateFile struct {
+	Version int               `json:"version"`
+	Classes map[string]string `json:"classes"`
+}
+
+// Store persists the input method last used per window class. Changes are
+// saved in the background shortly after they are made.
+type Store struct {
+	path      string
+	saveDelay time.Duration
+
+	mutex     sync.RWMutex
+	classes   map[string]string
+	dirty     bool        // changes not yet written
+	saveTimer *time.Timer // pending background save, if any
+
+	saveMutex sync.Mutex // serializes writes, so an older snapshot never wins
+}
+
+func NewStore(path string) *Store {
+	return &Store{
+		path:      path,
+		saveDelay: saveDelay,
+		classes:   make(map[string]string),
+	}
+}
+
+// DefaultPath returns the state file path under $XDG_STATE_HOME
+func DefaultPath() string {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			// Fallback to current directory if can't get home directory
+			return "./state.json"
+		}
+		stateDir = filepath.Join(homeDir, ".local", "state")
+	}
+
+	return filepath.Join(stateDir, "hypr-input-switcher", "state.json")
+}
+
+// Path returns the state file path
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Load reads the state file. A missing file leaves the store empty.
+func (s *Store) Load() error {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		logger.Debugf("State file not found at %s, starting empty", s.path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state stateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse state file %s: %w", s.path, err)
+	}
+
+	if state.Version != stateVersion {
+		return fmt.Errorf("unsupported state file version %d in %s", state.Version, s.path)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.classes = make(map[string]string, len(state.Classes))
+	for class, inputMethod := range state.Classes {
+		s.classes[class] = inputMethod
+	}
+
+	logger.Debugf("Loaded %d learned input methods from %s", len(s.classes), s.path)
+	return nil
+}
+
+// Get returns the input method last used by a window class
+func (s *Store) Get(class string) (string, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	inputMethod, exists := s.classes[class]
+	return inputMethod, exists
+}
+
+// Set records the input method used by a window class. The store is saved
+// in the background if the entry changed.
+func (s *Store) Set(class, inputMethod string) {
+	if class == "" || inputMethod == "" {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.classes[class] == inputMethod {
+		return
+	}
+	s.classes[class] = inputMethod
+	s.dirty = true
+
+	// A pending save picks up this change as well
+	if s.saveTimer == nil {
+		s.saveTimer = time.AfterFunc(s.saveDelay, s.backgroundSave)
+	}
+}
+
+// backgroundSave runs the save scheduled by Set
+func (s *Store) backgroundSave() {
+	s.mutex.Lock()
+	s.saveTimer = nil
+	s.mutex.Unlock()
+
+	if err := s.save(); err != nil {
+		logger.Warningf("Failed to save learned input methods: %v", err)
+	}
+}
+
+// Flush cancels the pending background save and saves now, for shutdown
+func (s *Store) Flush() error {
+	s.mutex.Lock()
+	if s.saveTimer != nil {
+		s.saveTimer.Stop()
+		s.saveTimer = nil
+	}
+	s.mutex.Unlock()
+
+	return s.save()
+}
+
+// save writes the state file if it has unsaved changes. The file is
+// replaced atomically, so a crash never leaves a truncated file behind.
+func (s *Store) save() error {
+	s.saveMutex.Lock()
+	defer s.saveMutex.Unlock()
+
+	s.mutex.Lock()
+	if !s.dirty {
+		s.mutex.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(stateFile{Version: stateVersion, Classes: s.classes}, "", "  ")
+	s.dirty = false
+	s.mutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if err := s.write(data); err != nil {
+		// Try again with the next save
+		s.mutex.Lock()
+		s.dirty = true
+		s.mutex.Unlock()
+		return err
+	}
+
+	logger.Tracef("Saved learned input methods to %s", s.path)
+	return nil
+}
+
+// write replaces the state file with data through a temporary file
+func (s *Store) write(data []byte) error {
+	stateDir := filepath.Dir(s.path)
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory %s: %w", stateDir, err)
+	}
+
+	tempFile, err := os.CreateTemp(stateDir, ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath) // No-op once renamed
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to sync state file: %w", err)
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to close state file: %w", err)
+	}
+
+	if err := os.Rename(tempPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readStateFile decodes the state file at path
+func readStateFile(t *testing.T, path string) stateFile {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state stateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("state file is not valid JSON: %v", err)
+	}
+	return state
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "state.json"))
+
+	if err := store.Load(); err != nil {
+		t.Fatalf("missing state file failed to load: %v", err)
+	}
+	if _, exists := store.Get("kitty"); exists {
+		t.Error("empty store returned an entry")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"valid", `{"version": 1, "classes": {"kitty": "chinese"}}`, ""},
+		{"version mismatch", `{"version": 2, "classes": {"kitty": "chinese"}}`, "unsupported state file version 2"},
+		{"missing version", `{"classes": {"kitty": "chinese"}}`, "unsupported state file version 0"},
+		{"corrupt", `{"version": 1, "classes": {"kitty": `, "failed to parse state file"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.json")
+			if err := os.WriteFile(path, []byte(test.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			store := NewStore(path)
+			err := store.Load()
+			inputMethod, exists := store.Get("kitty")
+
+			if test.err == "" {
+				if err != nil || inputMethod != "chinese" {
+					t.Errorf("got %q, %v, want chinese", inputMethod, err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("error %v does not contain %q", err, test.err)
+			}
+			if exists {
+				t.Error("a rejected state file was loaded")
+			}
+		})
+	}
+}
+
+func TestFlushReplacesFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hypr-input-switcher")
+	path := filepath.Join(dir, "state.json")
+
+	store := NewStore(path)
+	store.Set("kitty", "chinese")
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	store.Set("firefox", "english")
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	state := readStateFile(t, path)
+	if state.Version != stateVersion || state.Classes["kitty"] != "chinese" || state.Classes["firefox"] != "english" {
+		t.Errorf("unexpected state file: %+v", state)
+	}
+
+	// Temporary files are renamed over the state file, never left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("state directory holds %d files, want only the state file", len(entries))
+	}
+
+	reloaded := NewStore(path)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if inputMethod, _ := reloaded.Get("firefox"); inputMethod != "english" {
+		t.Errorf("reloaded store has firefox = %q, want english", inputMethod)
+	}
+}
+
+func TestSetSavesInBackground(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	store := NewStore(path)
+	store.saveDelay = 50 * time.Millisecond
+
+	store.Set("kitty", "chinese")
+	store.Set("kitty", "english")
+	store.Set("firefox", "english")
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("Set wrote the state file synchronously")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the background save never happened")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	state := readStateFile(t, path)
+	if state.Classes["kitty"] != "english" || state.Classes["firefox"] != "english" {
+		t.Errorf("background save wrote %+v, want the latest changes", state.Classes)
+	}
+}
+
+func TestFlushWithoutChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	store := NewStore(path)
+	store.Set("", "english")
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Flush wrote a state file without changes")
+	}
+}